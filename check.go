@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runCheck implements `genenv check`, which validates an existing output file against
+// the annotations of its template without writing anything
+func runCheck(args []string) int {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+
+	output := fs.String("output", ".env", "Output file to check (default depends on --format)")
+	fs.StringVar(output, "o", ".env", "Output file to check (default depends on --format)")
+
+	format := fs.String("format", "dotenv", "Format of the output file: dotenv, json, yaml, k8s-secret, k8s-split, sh, fish, powershell")
+	nest := fs.String("nest", "", "Separator the keys of json/yaml output were nested by")
+	maxLineSize := fs.Int("max-line-size", 0, "Maximum length of a single line in bytes (default: unlimited)")
+	profile := fs.String("profile", "", "Check against the template lines of this profile (default output: .env.<profile>)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv check [options] [template-file] [overlay-template...]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv check\n")
+		fmt.Fprintf(os.Stderr, "  genenv check .env.example -o .env.production\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	templates := []string{".env.example"}
+	if fs.NArg() > 0 {
+		templates = fs.Args()
+	}
+
+	formatType := generator.Format(*format)
+	if !isValidFormat(formatType) {
+		fmt.Printf("Error: Invalid format '%s'. Valid options are: dotenv, json, yaml, k8s-secret, k8s-split, sh, fish, powershell\n", *format)
+		return 1
+	}
+	if *profile != "" && !generator.IsValidProfile(*profile) {
+		fmt.Printf("Error: Invalid profile '%s'. Use letters, digits, _ and -\n", *profile)
+		return 1
+	}
+	if !flagSetPassed(fs, "output", "o") {
+		*output = profileOutputPath(defaultOutputPath(formatType), *profile)
+	}
+
+	config, err := withProject(generator.Config{
+		TemplatePath:  templates[0],
+		Overlays:      templates[1:],
+		OutputPath:    *output,
+		Format:        formatType,
+		NestSeparator: *nest,
+		MaxLineSize:   *maxLineSize,
+		Profile:       *profile,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	gen := generator.New(config)
+	violations, err := gen.Check()
+	if err != nil {
+		fmt.Printf("Error checking %s: %v\n", *output, err)
+		return 1
+	}
+
+	for _, violation := range violations {
+		fmt.Println(violation)
+	}
+	if len(violations) > 0 {
+		return 1
+	}
+
+	fmt.Printf("%s matches %s\n", *output, strings.Join(templates, ", "))
+	return 0
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "# @enum debug|info|warn\nLOG_LEVEL=info\n")
+	output := filepath.Join(filepath.Dir(template), "output.env")
+
+	os.WriteFile(output, []byte("LOG_LEVEL=verbose\n"), 0600)
+
+	exitCode, stdout, _ := runGenenv(t, binary, "check", "-o", output, template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, output+":1: LOG_LEVEL must be one of debug|info|warn")
+	assertContains(t, stdout, template+":1: @enum debug|info|warn")
+	assertNotContains(t, stdout, "verbose")
+
+	os.WriteFile(output, []byte("LOG_LEVEL=warn\n"), 0600)
+
+	exitCode, stdout, _ = runGenenv(t, binary, "check", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "matches")
+}
+
+func TestGenerateReportsSchemaViolations(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "# @required\nAPI_URL=\n")
+	output := filepath.Join(filepath.Dir(template), "output.env")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "-o", output, template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "do not match the template schema")
+	assertContains(t, stdout, output+":2: API_URL is required but empty")
+	assertFileExists(t, output)
+}
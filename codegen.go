@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// codegenTargets maps the languages of `genenv codegen` to their entry points
+var codegenTargets = map[string]func(args []string) int{
+	"go": runCodegenGo,
+	"ts": runCodegenTS,
+}
+
+// runCodegen implements `genenv codegen <language>`, which generates typed config code from a template
+func runCodegen(args []string) int {
+	if len(args) > 0 {
+		if run, ok := codegenTargets[args[0]]; ok {
+			return run(args[1:])
+		}
+	}
+
+	languages := make([]string, 0, len(codegenTargets))
+	for language := range codegenTargets {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	fmt.Fprintf(os.Stderr, "Usage: genenv codegen <language> [options] [template-file]\n\n")
+	fmt.Fprintf(os.Stderr, "Languages: %s\n", strings.Join(languages, ", "))
+	return 1
+}
+
+// runCodegenGo implements `genenv codegen go`
+func runCodegenGo(args []string) int {
+	fs := flag.NewFlagSet("codegen go", flag.ExitOnError)
+
+	pkg := fs.String("package", generator.DefaultGoPackage, "Package name of the generated file")
+	typeName := fs.String("type", generator.DefaultGoType, "Name of the generated struct")
+
+	output := fs.String("output", "", "File to write (default: standard output)")
+	fs.StringVar(output, "o", "", "File to write (default: standard output)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv codegen go [options] [template-file]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv codegen go --package config -o config/env.go\n")
+		fmt.Fprintf(os.Stderr, "  //go:generate genenv codegen go --package config -o env.go ../.env.example\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	templatePath := ".env.example"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		templatePath = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	config, err := withProject(generator.Config{TemplatePath: templatePath})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	schema, err := generator.New(config).Schema()
+	if err != nil {
+		fmt.Printf("Error reading template: %v\n", err)
+		return 1
+	}
+	source, err := schema.Go(generator.GoOptions{Package: *pkg, Type: *typeName})
+	if err != nil {
+		fmt.Printf("Error generating Go code: %v\n", err)
+		return 1
+	}
+
+	return writeGenerated(*output, source)
+}
+
+// runCodegenTS implements `genenv codegen ts`
+func runCodegenTS(args []string) int {
+	fs := flag.NewFlagSet("codegen ts", flag.ExitOnError)
+
+	output := fs.String("output", "env.d.ts", "Declaration file to write, or - for standard output")
+	fs.StringVar(output, "o", "env.d.ts", "Declaration file to write, or - for standard output")
+
+	schemaLibrary := fs.String("schema", "", "Also generate a schema module for this validator: zod, valibot")
+	schemaOutput := fs.String("schema-output", "env.schema.ts", "Schema module to write with --schema, or - for standard output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv codegen ts [options] [template-file]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv codegen ts -o src/env.d.ts\n")
+		fmt.Fprintf(os.Stderr, "  genenv codegen ts --schema zod --schema-output src/env.ts\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	templatePath := ".env.example"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		templatePath = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	config, err := withProject(generator.Config{TemplatePath: templatePath})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	schema, err := generator.New(config).Schema()
+	if err != nil {
+		fmt.Printf("Error reading template: %v\n", err)
+		return 1
+	}
+
+	var module []byte
+	if *schemaLibrary != "" {
+		module, err = schema.TypeScriptSchema(generator.Validator(*schemaLibrary))
+		if err != nil {
+			fmt.Printf("Error generating schema module: %v\n", err)
+			return 1
+		}
+	}
+
+	if code := writeGenerated(*output, schema.TypeScriptDeclarations()); code != 0 {
+		return code
+	}
+	if module != nil {
+		return writeGenerated(*schemaOutput, module)
+	}
+	return 0
+}
+
+// writeGenerated writes generated code to path, or to standard output when path is empty or -
+// An up-to-date file is left untouched so go generate doesn't bump its modification time.
+func writeGenerated(path string, content []byte) int {
+	if path == "" || path == "-" {
+		os.Stdout.Write(content)
+		return 0
+	}
+
+	if existing, err := os.ReadFile(path); err == nil && string(existing) == string(content) {
+		return 0
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCodegenGoCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "# Port the server listens on\n# @type port\nPORT=8080\n")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "codegen", "go", "--package", "settings", template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "package settings")
+	assertContains(t, stdout, "// Port the server listens on")
+	assertContains(t, stdout, "Port int `env:\"PORT\"`")
+
+	output := filepath.Join(filepath.Dir(template), "env.go")
+	exitCode, _, _ = runGenenv(t, binary, "codegen", "go", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	assertFileExists(t, output)
+
+	content, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	assertContains(t, string(content), "package config")
+	assertContains(t, string(content), "func Load() (*Config, error)")
+
+	exitCode, _, stderr := runGenenv(t, binary, "codegen", "cobol", template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stderr, "Languages: go, ts")
+}
+
+func TestCodegenTSCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "# @required\nAPI_URL=\nSESSION_SECRET=${session_secret}\n")
+	dir := filepath.Dir(template)
+	declarations := filepath.Join(dir, "env.d.ts")
+	module := filepath.Join(dir, "env.schema.ts")
+
+	exitCode, _, _ := runGenenv(t, binary, "codegen", "ts", "-o", declarations, "--schema", "zod", "--schema-output", module, template)
+	assertExitCode(t, exitCode, 0)
+
+	content, err := os.ReadFile(declarations)
+	if err != nil {
+		t.Fatalf("Failed to read declarations: %v", err)
+	}
+	assertContains(t, string(content), "API_URL: string;")
+	assertContains(t, string(content), "@secret")
+
+	content, err = os.ReadFile(module)
+	if err != nil {
+		t.Fatalf("Failed to read schema module: %v", err)
+	}
+	assertContains(t, string(content), `export const secretKeys = ["SESSION_SECRET"] as const;`)
+
+	exitCode, stdout, _ := runGenenv(t, binary, "codegen", "ts", "-o", "-", template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "interface ProcessEnv")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "codegen", "ts", "--schema", "yup", "-o", "-", template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "unknown validator")
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// configEnv names a project configuration file to use instead of the one found upward from
+// the current directory
+const configEnv = "GENENV_CONFIG"
+
+// setting is a generate option a project configuration file and a GENENV_* environment
+// variable can set; flags override both
+type setting struct {
+	name     string
+	flags    []string // Empty for the template, which is an argument
+	env      string
+	validate func(value string) string // Returns how to fix an invalid value
+}
+
+// settings lists the settings in the order `genenv config show` prints them
+var settings = []setting{
+	{name: "template", env: "GENENV_TEMPLATE"},
+	{name: "output", flags: []string{"output", "o"}, env: "GENENV_OUTPUT"},
+	{name: "length", flags: []string{"length", "l"}, env: "GENENV_LENGTH", validate: func(value string) string {
+		if length, err := strconv.Atoi(value); err != nil || length <= 0 {
+			return "Use a positive number"
+		}
+		return ""
+	}},
+	{name: "charset", flags: []string{"charset", "c"}, env: "GENENV_CHARSET", validate: func(value string) string {
+		if !isValidCharset(generator.CharsetType(value)) {
+			return "Valid options are: alphanumeric, alphabetic, uppercase, lowercase, numeric"
+		}
+		return ""
+	}},
+	{name: "dialect", flags: []string{"dialect"}, env: "GENENV_DIALECT", validate: func(value string) string {
+		if !isValidDialect(generator.Dialect(value)) {
+			return "Valid options are: dotenv, docker, compose, systemd"
+		}
+		return ""
+	}},
+	{name: "mode", flags: []string{"mode"}, env: "GENENV_MODE", validate: func(value string) string {
+		if _, err := parseFileMode(value); err != nil {
+			return "Use an octal permission such as 0600"
+		}
+		return ""
+	}},
+}
+
+// resolvedSetting is the effective value of a setting and where it came from: a flag, an
+// environment variable, a line of the project configuration file, or the default
+type resolvedSetting struct {
+	name   string
+	value  string
+	source string
+}
+
+// loadProject reads the project configuration file given with --config or GENENV_CONFIG, or
+// else the one found upward from the current directory; it returns nil without one
+func loadProject(path string) (*generator.ProjectConfig, error) {
+	if path == "" {
+		path = os.Getenv(configEnv)
+	}
+	if path == "" {
+		found, err := generator.FindProjectConfig(".")
+		if err != nil || found == "" {
+			return nil, err
+		}
+		path = found
+	}
+	return generator.LoadProjectConfig(relativePath(path))
+}
+
+// resolveSettings sets the flags of fs that were not passed from GENENV_* variables or the
+// project configuration, and returns every setting with its source
+// The template argument comes from the same sources when args has none. Paths in the
+// configuration file are relative to it, except the output of --recursive runs, which is
+// relative to each template like -o.
+func resolveSettings(fs *flag.FlagSet, project *generator.ProjectConfig, args []string, recursive bool) ([]resolvedSetting, error) {
+	var resolved []resolvedSetting
+	for _, s := range settings {
+		r := resolvedSetting{name: s.name}
+		switch {
+		case s.flags == nil && len(args) > 0:
+			r.value, r.source = args[0], "argument"
+		case s.flags != nil && flagSetPassed(fs, s.flags...):
+			r.value, r.source = fs.Lookup(s.flags[0]).Value.String(), "--"+s.flags[0]
+		case os.Getenv(s.env) != "":
+			r.value, r.source = os.Getenv(s.env), s.env
+		case project != nil && project.Settings[s.name].Line > 0:
+			ps := project.Settings[s.name]
+			r.value, r.source = ps.Value, project.Source(ps.Line)
+			if (s.name == "template" || (s.name == "output" && !recursive)) && !filepath.IsAbs(r.value) {
+				r.value = relativePath(filepath.Join(filepath.Dir(project.Path), r.value))
+			}
+		default:
+			if s.flags != nil {
+				r.value = fs.Lookup(s.flags[0]).DefValue
+			}
+			r.source = "default"
+		}
+
+		if r.source != "default" && r.source != "argument" && !strings.HasPrefix(r.source, "--") {
+			if s.validate != nil {
+				if hint := s.validate(r.value); hint != "" {
+					return nil, fmt.Errorf("invalid %s '%s' from %s. %s", s.name, r.value, r.source, hint)
+				}
+			}
+			if s.flags != nil {
+				if err := fs.Set(s.flags[0], r.value); err != nil {
+					return nil, fmt.Errorf("invalid %s '%s' from %s: %v", s.name, r.value, r.source, err)
+				}
+			}
+		}
+		resolved = append(resolved, r)
+	}
+	return resolved, nil
+}
+
+// settingValue returns the value of a resolved setting
+func settingValue(resolved []resolvedSetting, name string) string {
+	for _, r := range resolved {
+		if r.name == name {
+			return r.value
+		}
+	}
+	return ""
+}
+
+// relativePath shortens a path to be relative to the current directory when it lies below it
+func relativePath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(wd, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return path
+	}
+	return rel
+}
+
+// withProject adds the presets and key overrides of the project configuration to config, so
+// commands reading a template understand its ${name:preset=...} placeholders
+func withProject(config generator.Config) (generator.Config, error) {
+	project, err := loadProject("")
+	if err != nil || project == nil {
+		return config, err
+	}
+	config.Presets, config.KeyOverrides = project.Presets, project.Keys
+	return config, nil
+}
+
+// trustedHooks returns project when its hooks may run, or nil to skip them
+// Hooks are arbitrary shell commands, so they only run when --hooks is given, and never from
+// a configuration outside the repository of the current directory (or the current directory
+// itself outside a repository), which an untrusted checkout could otherwise place there.
+func trustedHooks(project *generator.ProjectConfig, enabled bool) (*generator.ProjectConfig, error) {
+	if project == nil || len(project.Hooks) == 0 {
+		return nil, nil
+	}
+	if !enabled {
+		fmt.Printf("Skipping the hooks of %s; pass --hooks to run them\n", project.Path)
+		return nil, nil
+	}
+
+	root, err := generator.FindRepoRoot(".")
+	if err == nil && root == "" {
+		root, err = filepath.Abs(".")
+	}
+	if err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(project.Path))
+	if err != nil {
+		return nil, err
+	}
+	// Compare resolved paths so a symlinked directory can't lead outside the root
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if rel, err := filepath.Rel(root, dir); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("refusing to run the hooks of %s, which is outside %s", project.Path, root)
+	}
+	return project, nil
+}
+
+// runHooks runs the commands of a hook stage of the project configuration with the shell,
+// in the directory of the configuration file, and stops at the first that fails
+// The template and output paths are passed as GENENV_TEMPLATE and GENENV_OUTPUT when known.
+func runHooks(project *generator.ProjectConfig, stage, template, output string) error {
+	if project == nil {
+		return nil
+	}
+	env := os.Environ()
+	for name, path := range map[string]string{"GENENV_TEMPLATE": template, "GENENV_OUTPUT": output} {
+		if path == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		env = append(env, name+"="+path)
+	}
+
+	for _, command := range project.Hooks[stage] {
+		cmd := exec.Command("sh", "-c", command)
+		if runtime.GOOS == "windows" {
+			cmd = exec.Command("cmd", "/C", command)
+		}
+		cmd.Dir = filepath.Dir(project.Path)
+		cmd.Env = env
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %v", stage, command, err)
+		}
+	}
+	return nil
+}
+
+// runConfig implements `genenv config show`, which prints the effective settings of a
+// generate run and where each one comes from
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+
+	configPath := fs.String("config", "", "Project configuration file (default: "+strings.Join(generator.ProjectConfigNames, ", ")+" found upward, or $"+configEnv+")")
+	output := fs.String("output", ".env", "Output file path")
+	fs.StringVar(output, "o", ".env", "Output file path")
+	length := fs.Int("length", 24, "Length of generated random values")
+	fs.IntVar(length, "l", 24, "Length of generated random values")
+	charset := fs.String("charset", "alphanumeric", "Character set for generated values")
+	fs.StringVar(charset, "c", "alphanumeric", "Character set for generated values")
+	fs.String("dialect", "dotenv", "Quoting rules of the dotenv output")
+	fs.String("mode", "", "Permissions of the output file in octal")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv config show [options] [template-file]\n\n")
+		fmt.Fprintf(os.Stderr, "Prints the settings genenv would use and where each one comes from:\n")
+		fmt.Fprintf(os.Stderr, "flags, then GENENV_* environment variables, then the project configuration file, then defaults.\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+	if fs.NArg() == 0 || fs.Arg(0) != "show" || fs.NArg() > 2 {
+		fs.Usage()
+		return 1
+	}
+
+	project, err := loadProject(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	resolved, err := resolveSettings(fs, project, fs.Args()[1:], false)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if project == nil {
+		fmt.Printf("Project configuration: none\n\n")
+	} else {
+		fmt.Printf("Project configuration: %s\n\n", project.Path)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "SETTING\tVALUE\tSOURCE\n")
+	for _, r := range resolved {
+		value := r.value
+		if value == "" {
+			value = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.name, value, r.source)
+	}
+	if project != nil {
+		for _, group := range []struct {
+			prefix  string
+			presets map[string]generator.Preset
+		}{{"presets", project.Presets}, {"keys", project.Keys}} {
+			for _, name := range sortedNames(group.presets) {
+				key := group.prefix + "." + name
+				fmt.Fprintf(w, "%s\t%s\t%s\n", key, group.presets[name], project.Source(project.Lines[key]))
+			}
+		}
+		for _, stage := range []string{generator.HookPre, generator.HookPost} {
+			key := "hooks." + stage
+			for _, command := range project.Hooks[stage] {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", key, command, project.Source(project.Lines[key]))
+			}
+		}
+	}
+	w.Flush()
+	return 0
+}
+
+// sortedNames returns the names of a preset map in order
+func sortedNames(presets map[string]generator.Preset) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestProjectConfig tests the precedence of flags, GENENV_* variables, the project
+// configuration and defaults, along with hooks, which only run with --hooks from inside the
+// repository, and `genenv config show`
+func TestProjectConfig(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "env"), 0755)
+	os.MkdirAll(filepath.Join(root, "services", "api"), 0755)
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	files := map[string]string{
+		".genenv.toml": `template = "env/.env.example"
+output = ".env.local"
+length = 12
+mode = "0640"
+
+[presets.pin]
+length = 6
+charset = "numeric"
+
+[keys.API_TOKEN]
+charset = "uppercase"
+
+[hooks]
+pre = "echo pre > hooks.log"
+post = ["echo \"post $(basename $GENENV_OUTPUT)\" >> hooks.log"]
+`,
+		"env/.env.example": "SECRET=${secret}\nPIN=${pin:preset=pin}\nAPI_TOKEN=${api_token}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	run := func(env []string, args ...string) (int, string) {
+		t.Helper()
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = filepath.Join(root, "services", "api")
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode(), string(out)
+		}
+		return 0, string(out)
+	}
+
+	// With no arguments the configuration supplies the template and the output
+	exitCode, stdout := run(nil)
+	assertExitCode(t, exitCode, 0)
+	output := filepath.Join(root, ".env.local")
+	env := parseEnvFile(readOutputFile(t, output))
+	if len(env["SECRET"]) != 12 {
+		t.Errorf("Expected a 12 character secret from the configuration, got %q\n%s", env["SECRET"], stdout)
+	}
+	if len(env["PIN"]) != 6 || strings.Trim(env["PIN"], "0123456789") != "" {
+		t.Errorf("Expected a 6 digit pin from the preset, got %q", env["PIN"])
+	}
+	if len(env["API_TOKEN"]) != 12 || strings.ToUpper(env["API_TOKEN"]) != env["API_TOKEN"] {
+		t.Errorf("Expected an uppercase token from the key override, got %q", env["API_TOKEN"])
+	}
+	if info, _ := os.Stat(output); info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 0640 from the configuration, got %o", info.Mode().Perm())
+	}
+	// Hooks only run with --hooks
+	assertContains(t, stdout, "Skipping the hooks of "+filepath.Join(root, ".genenv.toml")+"; pass --hooks to run them")
+	assertFileNotExists(t, filepath.Join(root, "hooks.log"))
+	exitCode, stdout = run(nil, "--hooks")
+	assertExitCode(t, exitCode, 0)
+	if hooks := readOutputFile(t, filepath.Join(root, "hooks.log")); hooks != "pre\npost .env.local\n" {
+		t.Errorf("Expected the pre and post hooks to run in the configuration directory, got %q\n%s", hooks, stdout)
+	}
+
+	// Environment variables override the configuration, and flags override both
+	exitCode, _ = run([]string{"GENENV_LENGTH=16"}, "--force", "--yes", "--no-backup")
+	assertExitCode(t, exitCode, 0)
+	if env = parseEnvFile(readOutputFile(t, output)); len(env["SECRET"]) != 16 {
+		t.Errorf("Expected a 16 character secret from GENENV_LENGTH, got %q", env["SECRET"])
+	}
+	exitCode, _ = run([]string{"GENENV_LENGTH=16"}, "--force", "--yes", "--no-backup", "-l", "20")
+	assertExitCode(t, exitCode, 0)
+	if env = parseEnvFile(readOutputFile(t, output)); len(env["SECRET"]) != 20 {
+		t.Errorf("Expected a 20 character secret from -l, got %q", env["SECRET"])
+	}
+
+	exitCode, stdout = run([]string{"GENENV_CHARSET=numeric"}, "config", "show", "-l", "20")
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Project configuration: "+filepath.Join(root, ".genenv.toml"))
+	for _, line := range [][]string{
+		{"template", filepath.Join(root, "env", ".env.example"), ".genenv.toml:1"},
+		{"output", output, ".genenv.toml:2"},
+		{"length", "20", "--length"},
+		{"charset", "numeric", "GENENV_CHARSET"},
+		{"dialect", "dotenv", "default"},
+		{"mode", "0640", ".genenv.toml:4"},
+		{"presets.pin", "length=6,charset=numeric", ".genenv.toml:7"},
+		{"keys.API_TOKEN", "charset=uppercase", ".genenv.toml:11"},
+	} {
+		if !containsFields(stdout, line) {
+			t.Errorf("Expected a line with %q in:\n%s", line, stdout)
+		}
+	}
+
+	exitCode, stdout = run([]string{"GENENV_DIALECT=bash"}, "config", "show")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "Error: invalid dialect 'bash' from GENENV_DIALECT")
+
+	// A failing hook stops the run
+	os.WriteFile(filepath.Join(root, ".genenv.toml"), []byte("template = \"env/.env.example\"\n[hooks]\npre = \"exit 3\"\n"), 0644)
+	exitCode, stdout = run(nil, "--hooks")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, `pre hook "exit 3" failed`)
+	assertFileNotExists(t, filepath.Join(root, "services", "api", ".env"))
+
+	// Hooks of a configuration outside the repository of the current directory never run
+	os.WriteFile(filepath.Join(root, ".genenv.toml"), []byte("template = \"env/.env.example\"\n[hooks]\npre = \"echo outside > hooks.log\"\n"), 0644)
+	os.MkdirAll(filepath.Join(root, "services", "api", ".git"), 0755)
+	exitCode, stdout = run(nil, "--hooks")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "Error: refusing to run the hooks of "+filepath.Join(root, ".genenv.toml"))
+	if hooks := readOutputFile(t, filepath.Join(root, "hooks.log")); strings.Contains(hooks, "outside") {
+		t.Errorf("Expected the hook outside the repository not to run, got %q", hooks)
+	}
+}
+
+// containsFields checks if any line of text consists of the given whitespace-separated
+// fields, each of which may end a longer field
+func containsFields(text string, fields []string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		got := strings.Fields(line)
+		if len(got) != len(fields) {
+			continue
+		}
+		match := true
+		for i := range fields {
+			match = match && strings.HasSuffix(got[i], fields[i])
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
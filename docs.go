@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runDocs implements `genenv docs`, which documents the keys of a template as a table
+func runDocs(args []string) int {
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+
+	format := fs.String("format", "markdown", "Markup of the table: markdown, html")
+
+	output := fs.String("output", "", "File to write (default: standard output)")
+	fs.StringVar(output, "o", "", "File to write (default: standard output)")
+
+	update := fs.String("update", "", "Replace the region between "+generator.DocsStartMarker+" and "+generator.DocsEndMarker+" of this file")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv docs [options] [template-file]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv docs .env.example\n")
+		fmt.Fprintf(os.Stderr, "  genenv docs .env.example --format html -o docs/env.html\n")
+		fmt.Fprintf(os.Stderr, "  genenv docs .env.example --update README.md\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	templatePath := ".env.example"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		templatePath = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	if *output != "" && *update != "" {
+		fmt.Println("Error: --output and --update cannot be used together")
+		return 1
+	}
+
+	config, err := withProject(generator.Config{TemplatePath: templatePath})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	entries, err := generator.New(config).DocEntries()
+	if err != nil {
+		fmt.Printf("Error reading template: %v\n", err)
+		return 1
+	}
+	content, err := generator.RenderDocs(entries, generator.DocsFormat(*format))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+
+	if *update != "" {
+		if err := generator.UpdateDocs(*update, content); err != nil {
+			fmt.Printf("Error updating docs: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Updated the environment variables of %s from %s\n", *update, templatePath)
+		return 0
+	}
+	return writeGenerated(*output, content)
+}
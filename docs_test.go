@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocsCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "# Database configuration\nDB_HOST=localhost\nDB_PASSWORD=${db_password}\nADMIN_PASSWORD=hunter2hunter2\n")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "docs", template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "| `DB_HOST` | Database configuration |  | `localhost` | no |  |")
+	assertContains(t, stdout, "| yes | random alphanumeric, 24 chars |")
+	assertNotContains(t, stdout, "hunter2")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "docs", "--format", "html", template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "<td><code>DB_HOST</code></td>")
+
+	readme := filepath.Join(filepath.Dir(template), "README.md")
+	os.WriteFile(readme, []byte("# App\n\n<!-- genenv:docs:start -->\n<!-- genenv:docs:end -->\n"), 0644)
+
+	exitCode, _, _ = runGenenv(t, binary, "docs", "--update", readme, template)
+	assertExitCode(t, exitCode, 0)
+	content, err := os.ReadFile(readme)
+	if err != nil {
+		t.Fatalf("Failed to read README: %v", err)
+	}
+	assertContains(t, string(content), "<!-- genenv:docs:start -->\n| Key |")
+	assertContains(t, string(content), "| `DB_PASSWORD` |")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "docs", "--format", "pdf", template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "unknown docs format")
+}
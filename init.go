@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runInit implements `genenv init`, which writes a template from an existing env file
+func runInit(args []string) int {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+
+	from := fs.String("from", ".env", "Env file to turn into a template")
+
+	output := fs.String("output", ".env.example", "Template file to write")
+	fs.StringVar(output, "o", ".env.example", "Template file to write")
+
+	force := fs.Bool("force", false, "Overwrite an existing template")
+	fs.BoolVar(force, "f", false, "Overwrite an existing template")
+
+	mode := fs.String("mode", "", "Permissions of the template in octal (default: keep existing, 0644 for new files)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv init [--from <env-file>] [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv init --from .env\n")
+		fmt.Fprintf(os.Stderr, "  genenv init --from .env.production -o .env.production.example\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 1
+	}
+
+	fileMode, err := parseFileMode(*mode)
+	if err != nil {
+		fmt.Printf("Error: Invalid mode '%s'. Use an octal permission such as 0644\n", *mode)
+		return 1
+	}
+
+	if fileExists(*output) && !*force {
+		fmt.Printf("Error: %s already exists. Use --force to overwrite it\n", *output)
+		return 1
+	}
+
+	gen := generator.New(generator.Config{
+		TemplatePath: *output,
+		FileMode:     fileMode,
+	})
+	if err := gen.InitTemplate(*from); err != nil {
+		fmt.Printf("Error generating template: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Successfully generated %s from %s\n", *output, *from)
+	return 0
+}
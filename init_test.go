@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInitCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	envPath := filepath.Join(tmpDir, ".env")
+	templatePath := filepath.Join(tmpDir, ".env.example")
+
+	os.WriteFile(envPath, []byte("# Database\nDB_HOST=localhost\nDB_PASSWORD=hunter2hunter2\n"), 0600)
+
+	exitCode, stdout, _ := runGenenv(t, binary, "init", "--from", envPath, "-o", templatePath)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Successfully generated")
+
+	content := readOutputFile(t, templatePath)
+	assertContains(t, content, "# Database\nDB_HOST=localhost\nDB_PASSWORD=${db_password:length=14}")
+
+	// The template can be fed straight back into genenv
+	output := filepath.Join(tmpDir, "generated.env")
+	exitCode, _, _ = runGenenv(t, binary, "-o", output, templatePath)
+	assertExitCode(t, exitCode, 0)
+	envVars := parseEnvFile(readOutputFile(t, output))
+	assertValueLength(t, envVars["DB_PASSWORD"], 14)
+
+	// An existing template is only overwritten with --force
+	exitCode, stdout, _ = runGenenv(t, binary, "init", "--from", envPath, "-o", templatePath)
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code when the template exists")
+	}
+	assertContains(t, stdout, "--force")
+
+	exitCode, _, _ = runGenenv(t, binary, "init", "--from", envPath, "-o", templatePath, "--force")
+	assertExitCode(t, exitCode, 0)
+}
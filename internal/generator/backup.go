@@ -0,0 +1,321 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBackupRetention is the number of backups kept per output file
+	DefaultBackupRetention = 10
+
+	// backupTimeFormat is the timestamp layout used in backup IDs
+	backupTimeFormat = "20060102T150405"
+	// backupInfix separates the output path from the backup ID
+	backupInfix = ".bak."
+	// backupSecretsSuffix names the list of secret files backed up along with an output backup
+	backupSecretsSuffix = ".secrets"
+)
+
+// Backup describes a saved copy of an output file
+type Backup struct {
+	ID      string    // Timestamp with an optional -N suffix, e.g. 20261016T120000
+	Path    string    // Location of the backup file
+	Time    time.Time // When the backup was taken
+	Secrets []string  // Secret files of file= placeholders restored along with the output file
+	seq     int       // Disambiguates backups taken within the same second
+}
+
+// BackupPath returns the path of the backup taken during the last Generate or Restore, if any
+func (g *Generator) BackupPath() string {
+	return g.backupPath
+}
+
+// Backups lists the backups of the output file, oldest first
+func (g *Generator) Backups() ([]Backup, error) {
+	return listBackups(g.config.OutputPath)
+}
+
+// Restore replaces the output file with the backup identified by id, along with the secret
+// files backed up with it
+// An empty id selects the latest backup. The current files are backed up first,
+// so a restore can itself be undone.
+func (g *Generator) Restore(id string) (Backup, error) {
+	backups, err := listBackups(g.config.OutputPath)
+	if err != nil {
+		return Backup{}, err
+	}
+	if len(backups) == 0 {
+		return Backup{}, fmt.Errorf("no backups found for %s", g.config.OutputPath)
+	}
+
+	chosen := backups[len(backups)-1]
+	if id != "" {
+		found := false
+		for _, backup := range backups {
+			if backup.ID == id {
+				chosen, found = backup, true
+				break
+			}
+		}
+		if !found {
+			return Backup{}, fmt.Errorf("backup %s not found for %s", id, g.config.OutputPath)
+		}
+	}
+
+	unlock, err := g.lockOutputFile()
+	if err != nil {
+		return Backup{}, err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(chosen.Path)
+	if err != nil {
+		return Backup{}, fmt.Errorf("failed to read backup: %w", err)
+	}
+	secrets, err := backupSecretPaths(g.config.OutputPath, chosen.Path)
+	if err != nil {
+		return Backup{}, err
+	}
+
+	if err := g.backupOutputFile(secrets); err != nil {
+		return Backup{}, err
+	}
+
+	// Secret files go first so the env file never points at a value that wasn't restored
+	for _, secret := range secrets {
+		content, err := os.ReadFile(secret + backupInfix + chosen.ID)
+		if err != nil {
+			return Backup{}, fmt.Errorf("failed to read backup of secret file: %w", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(secret), 0700); err != nil {
+			return Backup{}, fmt.Errorf("failed to create directory for secret file: %w", err)
+		}
+		if err := writeFileAtomic(secret, content, SecretFileMode); err != nil {
+			return Backup{}, fmt.Errorf("failed to restore secret file: %w", err)
+		}
+	}
+	chosen.Secrets = secrets
+
+	if err := writeFileAtomic(g.config.OutputPath, data, g.config.FileMode); err != nil {
+		return Backup{}, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return chosen, nil
+}
+
+// backupOutputFile saves a copy of the current output file and of the given secret files, and
+// prunes old backups
+// It does nothing when backups are disabled or the output file does not exist yet
+func (g *Generator) backupOutputFile(secrets []string) error {
+	if g.config.NoBackup {
+		return nil
+	}
+
+	path := g.config.OutputPath
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up output file: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to back up output file: %w", err)
+	}
+
+	backupPath, err := nextBackupPath(path, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Backups hold the same secrets, so they get the same permissions as the original
+	if err := writeFileAtomic(backupPath, data, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to back up output file: %w", err)
+	}
+	if err := backupSecretFiles(path, backupPath, secrets); err != nil {
+		return err
+	}
+	g.backupPath = backupPath
+
+	return pruneBackups(path, g.config.BackupRetention)
+}
+
+// backupSecretFiles copies the secret files that exist next to themselves, with the ID of the
+// output backup, and lists them next to the output backup so a restore finds them
+// Paths are listed relative to the directory of the output file like file= paths.
+func backupSecretFiles(outputPath, backupPath string, secrets []string) error {
+	id := strings.TrimPrefix(filepath.Base(backupPath), filepath.Base(outputPath)+backupInfix)
+	var listed []string
+	for _, secret := range secrets {
+		info, err := os.Stat(secret)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to back up secret file: %w", err)
+		}
+		data, err := os.ReadFile(secret)
+		if err != nil {
+			return fmt.Errorf("failed to back up secret file: %w", err)
+		}
+		if err := writeFileAtomic(secret+backupInfix+id, data, info.Mode().Perm()); err != nil {
+			return fmt.Errorf("failed to back up secret file: %w", err)
+		}
+
+		if rel, err := filepath.Rel(filepath.Dir(outputPath), secret); err == nil && !filepath.IsAbs(secret) {
+			secret = rel
+		}
+		listed = append(listed, filepath.ToSlash(secret))
+	}
+	if len(listed) == 0 {
+		return nil
+	}
+	if err := writeFileAtomic(backupPath+backupSecretsSuffix, []byte(strings.Join(listed, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to back up secret file: %w", err)
+	}
+	return nil
+}
+
+// backupSecretPaths returns the secret files backed up along with an output backup
+func backupSecretPaths(outputPath, backupPath string) ([]string, error) {
+	data, err := os.ReadFile(backupPath + backupSecretsSuffix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var secrets []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		secret := filepath.FromSlash(line)
+		if !filepath.IsAbs(secret) {
+			secret = filepath.Join(filepath.Dir(outputPath), secret)
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// nextBackupPath returns an unused backup path for the given time
+// Backups taken within the same second get an increasing -N suffix so they keep their order
+func nextBackupPath(path string, now time.Time) (string, error) {
+	id := now.Format(backupTimeFormat)
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return "", err
+	}
+
+	seq := -1
+	for _, backup := range backups {
+		if strings.HasPrefix(backup.ID, id) && backup.seq > seq {
+			seq = backup.seq
+		}
+	}
+
+	candidate := path + backupInfix + id
+	if seq >= 0 {
+		candidate = fmt.Sprintf("%s-%d", candidate, seq+1)
+	}
+
+	return candidate, nil
+}
+
+// listBackups returns the backups of path sorted from oldest to newest
+func listBackups(path string) ([]Backup, error) {
+	dir := filepath.Dir(path)
+	prefix := filepath.Base(path) + backupInfix
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	var backups []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		backup, ok := parseBackupID(strings.TrimPrefix(name, prefix))
+		if !ok {
+			continue
+		}
+		backup.Path = filepath.Join(dir, name)
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.Before(backups[j].Time)
+		}
+		return backups[i].seq < backups[j].seq
+	})
+
+	return backups, nil
+}
+
+// parseBackupID parses a backup ID of the form 20060102T150405[-N]
+func parseBackupID(id string) (Backup, bool) {
+	stamp, suffix, hasSuffix := strings.Cut(id, "-")
+
+	t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+	if err != nil {
+		return Backup{}, false
+	}
+
+	seq := 0
+	if hasSuffix {
+		seq, err = strconv.Atoi(suffix)
+		if err != nil || seq <= 0 {
+			return Backup{}, false
+		}
+	}
+
+	return Backup{ID: id, Time: t, seq: seq}, true
+}
+
+// pruneBackups removes the oldest backups of path beyond the retention limit
+func pruneBackups(path string, keep int) error {
+	if keep <= 0 {
+		keep = DefaultBackupRetention
+	}
+
+	backups, err := listBackups(path)
+	if err != nil {
+		return err
+	}
+
+	for len(backups) > keep {
+		secrets, err := backupSecretPaths(path, backups[0].Path)
+		if err != nil {
+			return err
+		}
+		for _, secret := range append(secrets, path) {
+			if err := os.Remove(secret + backupInfix + backups[0].ID); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove old backup: %w", err)
+			}
+		}
+		if err := os.Remove(backups[0].Path + backupSecretsSuffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGeneratorForceCreatesBackup tests that --force saves the previous file before overwriting it
+func TestGeneratorForceCreatesBackup(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("SECRET=${secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(outputPath, []byte("SECRET=old_secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	// Without --force nothing is destroyed, so no backup is taken
+	gen := New(Config{TemplatePath: templatePath, OutputPath: outputPath})
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	if gen.BackupPath() != "" {
+		t.Errorf("Unexpected backup without --force: %s", gen.BackupPath())
+	}
+
+	gen = New(Config{TemplatePath: templatePath, OutputPath: outputPath, Force: true})
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	backupPath := gen.BackupPath()
+	if !strings.HasPrefix(filepath.Base(backupPath), ".env.bak.") {
+		t.Fatalf("Unexpected backup path: %q", backupPath)
+	}
+
+	content, err := os.ReadFile(backupPath)
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if string(content) != "SECRET=old_secret\n" {
+		t.Errorf("Backup does not hold the previous content, got %q", content)
+	}
+
+	info, _ := os.Stat(backupPath)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Backup should keep the original mode 600, got %o", info.Mode().Perm())
+	}
+
+	// NoBackup skips the backup
+	gen = New(Config{TemplatePath: templatePath, OutputPath: outputPath, Force: true, NoBackup: true})
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	if gen.BackupPath() != "" {
+		t.Errorf("Unexpected backup with NoBackup: %s", gen.BackupPath())
+	}
+}
+
+// TestGeneratorBackupRetention tests that old backups are pruned beyond the retention limit
+func TestGeneratorBackupRetention(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outputPath := filepath.Join(tempDir, ".env")
+	gen := New(Config{OutputPath: outputPath, BackupRetention: 2})
+
+	for i := 0; i < 4; i++ {
+		if err := os.WriteFile(outputPath, []byte(strings.Repeat("x", i)), 0600); err != nil {
+			t.Fatalf("Failed to write output file: %v", err)
+		}
+		if err := gen.backupOutputFile(nil); err != nil {
+			t.Fatalf("Failed to back up output file: %v", err)
+		}
+	}
+
+	backups, err := gen.Backups()
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v", err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %d", len(backups))
+	}
+
+	// The newest backups are kept
+	content, _ := os.ReadFile(backups[1].Path)
+	if string(content) != "xxx" {
+		t.Errorf("Expected the latest backup to be kept, got %q", content)
+	}
+}
+
+// TestGeneratorRestore tests restoring the latest backup and a backup by ID
+func TestGeneratorRestore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outputPath := filepath.Join(tempDir, ".env")
+	gen := New(Config{OutputPath: outputPath})
+
+	if _, err := gen.Restore(""); err == nil {
+		t.Error("Restore should fail without backups")
+	}
+
+	stamp := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	for i, content := range []string{"FIRST=1\n", "SECOND=1\n"} {
+		path := outputPath + ".bak." + stamp.Add(time.Duration(i)*time.Second).Format(backupTimeFormat)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("Failed to write backup: %v", err)
+		}
+	}
+	if err := os.WriteFile(outputPath, []byte("CURRENT=1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write output file: %v", err)
+	}
+
+	backup, err := gen.Restore("")
+	if err != nil {
+		t.Fatalf("Failed to restore latest backup: %v", err)
+	}
+	if backup.ID != "20261016T120001" {
+		t.Errorf("Expected latest backup, got %s", backup.ID)
+	}
+	content, _ := os.ReadFile(outputPath)
+	if string(content) != "SECOND=1\n" {
+		t.Errorf("Latest backup was not restored, got %q", content)
+	}
+
+	// The replaced file was backed up so the restore can be undone
+	saved, _ := os.ReadFile(gen.BackupPath())
+	if string(saved) != "CURRENT=1\n" {
+		t.Errorf("Current file was not backed up before restoring, got %q", saved)
+	}
+
+	if _, err := gen.Restore("20261016T120000"); err != nil {
+		t.Fatalf("Failed to restore backup by ID: %v", err)
+	}
+	content, _ = os.ReadFile(outputPath)
+	if string(content) != "FIRST=1\n" {
+		t.Errorf("Backup by ID was not restored, got %q", content)
+	}
+
+	if _, err := gen.Restore("19990101T000000"); err == nil {
+		t.Error("Restore should fail for an unknown backup ID")
+	}
+}
+
+// TestGeneratorBackupSecretFiles tests that --force backs up the secret files it overwrites and
+// that restoring brings them back
+func TestGeneratorBackupSecretFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("DB_PASSWORD_FILE=${db_pw:file=./secrets/db_pw}\nPLAIN=${plain}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	outputPath := filepath.Join(tempDir, ".env")
+	secretPath := filepath.Join(tempDir, "secrets", "db_pw")
+
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	original, _ := os.ReadFile(secretPath)
+
+	gen := New(Config{TemplatePath: templatePath, OutputPath: outputPath, Force: true})
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to regenerate .env file: %v", err)
+	}
+	id := strings.TrimPrefix(filepath.Base(gen.BackupPath()), ".env.bak.")
+	saved, err := os.ReadFile(secretPath + ".bak." + id)
+	if err != nil || string(saved) != string(original) {
+		t.Fatalf("Expected the previous secret in the backup, got %q, %v", saved, err)
+	}
+	if info, _ := os.Stat(secretPath + ".bak." + id); info.Mode().Perm() != SecretFileMode {
+		t.Errorf("Expected the secret backup to keep mode %o, got %o", SecretFileMode, info.Mode().Perm())
+	}
+
+	backup, err := New(Config{OutputPath: outputPath}).Restore(id)
+	if err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+	if len(backup.Secrets) != 1 || backup.Secrets[0] != secretPath {
+		t.Errorf("Expected the secret file to be restored, got %v", backup.Secrets)
+	}
+	if restored, _ := os.ReadFile(secretPath); string(restored) != string(original) {
+		t.Errorf("Expected the previous secret to be restored, got %q", restored)
+	}
+
+	// Pruning removes the secret backups with the output backup
+	if err := pruneBackups(outputPath, 1); err != nil {
+		t.Fatalf("Failed to prune backups: %v", err)
+	}
+	if _, err := os.Stat(secretPath + ".bak." + id); !os.IsNotExist(err) {
+		t.Errorf("Expected the secret backup to be pruned, got %v", err)
+	}
+	if _, err := os.Stat(outputPath + ".bak." + id + backupSecretsSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the list of secret backups to be pruned, got %v", err)
+	}
+}
+
+// TestNextBackupPathAvoidsCollisions tests that backups taken in the same second get distinct IDs
+func TestNextBackupPathAvoidsCollisions(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outputPath := filepath.Join(tempDir, ".env")
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	first, err := nextBackupPath(outputPath, now)
+	if err != nil {
+		t.Fatalf("Failed to get backup path: %v", err)
+	}
+	if err := os.WriteFile(first, nil, 0600); err != nil {
+		t.Fatalf("Failed to write backup: %v", err)
+	}
+
+	second, err := nextBackupPath(outputPath, now)
+	if err != nil {
+		t.Fatalf("Failed to get backup path: %v", err)
+	}
+	if second != first+"-1" {
+		t.Errorf("Expected %s-1, got %s", first, second)
+	}
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// DefaultGoPackage and DefaultGoType name the package and struct of generated Go code
+const (
+	DefaultGoPackage = "config"
+	DefaultGoType    = "Config"
+)
+
+// GoOptions configures the Go code generated from a schema
+type GoOptions struct {
+	Package string // Defaults to DefaultGoPackage
+	Type    string // Name of the struct; defaults to DefaultGoType
+}
+
+// goInitialisms are the words spelled in capitals in Go identifiers, as golint expects
+var goInitialisms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "CPU": true, "CSS": true, "DB": true, "DNS": true,
+	"EOF": true, "GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "JWT": true, "LHS": true, "QPS": true, "RAM": true, "RHS": true, "RPC": true,
+	"SLA": true, "SMTP": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true,
+	"UDP": true, "UI": true, "UID": true, "URI": true, "URL": true, "UTF8": true, "UUID": true,
+	"VM": true, "XML": true, "XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// goFieldName turns an env key such as DATABASE_URL into an exported Go name such as DatabaseURL
+func goFieldName(key string) string {
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		upper := strings.ToUpper(word)
+		if goInitialisms[upper] {
+			name.WriteString(upper)
+			continue
+		}
+		lower := []rune(strings.ToLower(word))
+		lower[0] = unicode.ToUpper(lower[0])
+		name.WriteString(string(lower))
+	}
+
+	result := name.String()
+	if result == "" || !unicode.IsLetter([]rune(result)[0]) {
+		result = "Env" + result
+	}
+	return result
+}
+
+// goType returns the Go type a field is decoded into
+func goType(fieldType FieldType) string {
+	switch fieldType {
+	case TypeInt, TypePort:
+		return "int"
+	case TypeFloat:
+		return "float64"
+	case TypeBool:
+		return "bool"
+	case TypeDuration:
+		return "time.Duration"
+	default:
+		return "string"
+	}
+}
+
+// goWriter accumulates generated Go source and the imports it needs
+type goWriter struct {
+	buf     bytes.Buffer
+	imports map[string]bool
+}
+
+func (w *goWriter) printf(format string, args ...any) {
+	fmt.Fprintf(&w.buf, format, args...)
+}
+
+// use records an import of the generated file
+func (w *goWriter) use(path string) {
+	w.imports[path] = true
+}
+
+// Go generates a Go source file with a struct holding every key of the schema and a Load
+// function that reads it from the environment and checks the annotations. The output only
+// depends on the template, so it can be regenerated by go generate.
+func (s *Schema) Go(options GoOptions) ([]byte, error) {
+	if options.Package == "" {
+		options.Package = DefaultGoPackage
+	}
+	if options.Type == "" {
+		options.Type = DefaultGoType
+	}
+	if !token.IsIdentifier(options.Package) {
+		return nil, fmt.Errorf("invalid package name %q", options.Package)
+	}
+	if !token.IsIdentifier(options.Type) || !token.IsExported(options.Type) {
+		return nil, fmt.Errorf("invalid type name %q; it must be an exported Go identifier", options.Type)
+	}
+
+	names := make(map[string]string)
+	for _, field := range s.Fields {
+		name := goFieldName(field.Key)
+		if other, ok := names[name]; ok {
+			return nil, fmt.Errorf("%s and %s both map to the Go field %s", other, field.Key, name)
+		}
+		names[name] = field.Key
+	}
+
+	body := &goWriter{imports: map[string]bool{"errors": true, "os": true}}
+	s.writeGoStruct(body, options.Type)
+	s.writeGoLoad(body, options.Type)
+
+	out := &goWriter{}
+	out.printf("// Code generated by genenv from %s; DO NOT EDIT.\n\n", s.TemplatePath)
+	out.printf("package %s\n\n", options.Package)
+	out.printf("import (\n")
+	imports := make([]string, 0, len(body.imports))
+	for path := range body.imports {
+		imports = append(imports, path)
+	}
+	slices.Sort(imports)
+	for _, path := range imports {
+		out.printf("\t%q\n", path)
+	}
+	out.printf(")\n\n")
+	out.buf.Write(body.buf.Bytes())
+
+	source, err := format.Source(out.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return source, nil
+}
+
+// writeGoStruct writes the struct with the comment group of each key as its doc comment
+func (s *Schema) writeGoStruct(w *goWriter, typeName string) {
+	w.printf("// %s holds the environment variables declared in %s\n", typeName, s.TemplatePath)
+	w.printf("type %s struct {\n", typeName)
+	for i, field := range s.Fields {
+		if i > 0 {
+			w.printf("\n")
+		}
+		name := goFieldName(field.Key)
+		if len(field.Comments) == 0 {
+			w.printf("\t// %s is read from %s\n", name, field.Key)
+		}
+		for _, comment := range field.Comments {
+			w.printf("\t// %s\n", comment)
+		}
+		if field.Type == TypeDuration {
+			w.use("time")
+		}
+		w.printf("\t%s %s `env:%q`\n", name, goType(field.Type), field.Key)
+	}
+	w.printf("}\n\n")
+}
+
+// writeGoLoad writes the Load function
+// Its helpers and patterns are locals so they can't collide with the identifiers of the
+// package the file is generated into, and errors never quote values, which may be secrets.
+func (s *Schema) writeGoLoad(w *goWriter, typeName string) {
+	w.printf("// Load reads %s from the environment and validates it against the annotations of %s\n", typeName, s.TemplatePath)
+	w.printf("// Unset or empty variables fall back to their value in the template. Every problem is\n")
+	w.printf("// reported, joined into a single error.\n")
+	w.printf("func Load() (*%s, error) {\n", typeName)
+	w.printf("\tvar cfg %s\n\tvar errs []error\n\n", typeName)
+
+	w.printf("\t// lookup returns the value of an environment variable, or def when it is unset or empty\n")
+	w.printf("\tlookup := func(key, def string) string {\n")
+	w.printf("\t\tif value := os.Getenv(key); value != \"\" {\n\t\t\treturn value\n\t\t}\n\t\treturn def\n\t}\n\n")
+
+	for _, field := range s.Fields {
+		writeGoField(w, field)
+	}
+	w.printf("\tif err := errors.Join(errs...); err != nil {\n\t\treturn nil, err\n\t}\n")
+	w.printf("\treturn &cfg, nil\n}\n")
+}
+
+// writeGoField writes the statements of Load that decode and check a single field
+func writeGoField(w *goWriter, field *Field) {
+	name := goFieldName(field.Key)
+	key := strconv.Quote(field.Key)
+	typeError := strconv.Quote(field.Key + " must be of type " + string(field.Type))
+
+	w.printf("\tif value := lookup(%s, %s); value != \"\" {\n", key, strconv.Quote(field.Default))
+	switch field.Type {
+	case TypeInt:
+		w.use("strconv")
+		w.printf("\t\tn, err := strconv.Atoi(value)\n")
+		w.printf("\t\tif err != nil {\n\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = n\n", name)
+	case TypePort:
+		w.use("strconv")
+		w.printf("\t\tn, err := strconv.Atoi(value)\n")
+		w.printf("\t\tif err != nil || n < 1 || n > 65535 {\n\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = n\n", name)
+	case TypeFloat:
+		w.use("strconv")
+		w.printf("\t\tf, err := strconv.ParseFloat(value, 64)\n")
+		w.printf("\t\tif err != nil {\n\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = f\n", name)
+	case TypeBool:
+		w.use("strconv")
+		w.printf("\t\tb, err := strconv.ParseBool(value)\n")
+		w.printf("\t\tif err != nil {\n\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = b\n", name)
+	case TypeDuration:
+		w.use("time")
+		w.printf("\t\td, err := time.ParseDuration(value)\n")
+		w.printf("\t\tif err != nil {\n\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = d\n", name)
+	case TypeURL:
+		w.use("net/url")
+		w.printf("\t\tif u, err := url.Parse(value); err != nil || u.Scheme == \"\" || u.Host == \"\" {\n")
+		w.printf("\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = value\n", name)
+	case TypeEmail:
+		w.use("net/mail")
+		w.printf("\t\tif address, err := mail.ParseAddress(value); err != nil || address.Address != value {\n")
+		w.printf("\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n", typeError)
+		w.printf("\t\tcfg.%s = value\n", name)
+	default:
+		w.printf("\t\tcfg.%s = value\n", name)
+	}
+
+	if field.Enum != nil {
+		w.use("slices")
+		quoted := make([]string, len(field.Enum))
+		for i, value := range field.Enum {
+			quoted[i] = strconv.Quote(value)
+		}
+		w.printf("\t\tif !slices.Contains([]string{%s}, value) {\n", strings.Join(quoted, ", "))
+		w.printf("\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n",
+			strconv.Quote(field.Key+" must be one of "+strings.Join(field.Enum, "|")))
+	}
+	if field.Pattern != nil {
+		w.use("regexp")
+		w.printf("\t\tif !regexp.MustCompile(%s).MatchString(value) {\n", strconv.Quote(field.Pattern.String()))
+		w.printf("\t\t\terrs = append(errs, errors.New(%s))\n\t\t}\n",
+			strconv.Quote(field.Key+" must match "+field.Pattern.String()))
+	}
+
+	if field.Required {
+		w.printf("\t} else {\n\t\terrs = append(errs, errors.New(%s))\n", strconv.Quote(field.Key+" is required"))
+	}
+	w.printf("\t}\n\n")
+}
@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGoFieldName tests turning env keys into Go field names
+func TestGoFieldName(t *testing.T) {
+	tests := map[string]string{
+		"DATABASE_URL": "DatabaseURL",
+		"API_KEY":      "APIKey",
+		"port":         "Port",
+		"HTTP_TIMEOUT": "HTTPTimeout",
+		"_1PASSWORD":   "Env1password",
+	}
+	for key, expected := range tests {
+		if name := goFieldName(key); name != expected {
+			t.Errorf("goFieldName(%q) = %q, expected %q", key, name, expected)
+		}
+	}
+}
+
+// TestSchemaGo tests the generated struct, tags and doc comments
+func TestSchemaGo(t *testing.T) {
+	template := `# Public URL of the API
+# @type url
+# @required
+API_URL=
+
+# @type port
+PORT=8080
+
+# @enum debug|info|warn
+LOG_LEVEL=info
+
+# @type duration
+TIMEOUT=30s
+
+SESSION_SECRET=${session_secret}
+`
+	schema, err := parseSchema(".env.example", strings.Split(template, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	source, err := schema.Go(GoOptions{Package: "settings"})
+	if err != nil {
+		t.Fatalf("Failed to generate Go code: %v", err)
+	}
+	code := string(source)
+
+	for _, expected := range []string{
+		"// Code generated by genenv from .env.example; DO NOT EDIT.",
+		"package settings",
+		"type Config struct {",
+		"// Public URL of the API\n\tAPIURL string `env:\"API_URL\"`",
+		"Port int `env:\"PORT\"`",
+		"Timeout time.Duration `env:\"TIMEOUT\"`",
+		"// SessionSecret is read from SESSION_SECRET",
+		`lookup("PORT", "8080")`,
+		`lookup("SESSION_SECRET", "")`,
+		`errors.New("API_URL is required")`,
+		"func Load() (*Config, error) {",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Generated code does not contain %q:\n%s", expected, code)
+		}
+	}
+	if strings.Contains(code, "@type") {
+		t.Errorf("Annotations should not be copied into doc comments:\n%s", code)
+	}
+
+	// The same template always produces the same code
+	again, _ := schema.Go(GoOptions{Package: "settings"})
+	if string(again) != code {
+		t.Error("Generated code is not deterministic")
+	}
+
+	if _, err := schema.Go(GoOptions{Type: "config"}); err == nil {
+		t.Error("Expected an error for an unexported type name")
+	}
+}
+
+// TestSchemaGoCollision tests that keys mapping to the same Go name are rejected
+func TestSchemaGoCollision(t *testing.T) {
+	schema, err := parseSchema(".env.example", []string{"API_URL=", "API__URL="})
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	if _, err := schema.Go(GoOptions{}); err == nil {
+		t.Error("Expected an error for keys that map to the same Go field")
+	}
+}
+
+// TestSchemaGoLoad compiles the generated code and checks Load against real environments
+func TestSchemaGoLoad(t *testing.T) {
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go toolchain not available")
+	}
+
+	template := `# @type url
+# @required
+API_URL=
+# @type port
+PORT=8080
+# @type bool
+DEBUG=false
+# @enum debug|info|warn
+LOG_LEVEL=info
+# @pattern ^sk_
+STRIPE_KEY=sk_test
+`
+	schema, err := parseSchema(".env.example", strings.Split(template, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	source, err := schema.Go(GoOptions{Package: "main"})
+	if err != nil {
+		t.Fatalf("Failed to generate Go code: %v", err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"go.mod":    "module example.com/app\n\ngo 1.21\n",
+		"config.go": string(source),
+		"main.go": `package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// Names a generated file must leave to the package
+var stripeKeyPattern = "unrelated"
+
+func lookup() string { return stripeKeyPattern }
+
+func main() {
+	_ = lookup()
+	cfg, err := Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s %d %t %s %s\n", cfg.APIURL, cfg.Port, cfg.Debug, cfg.LogLevel, cfg.StripeKey)
+}
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	binary := filepath.Join(tempDir, "app")
+	build := exec.Command(goBinary, "build", "-o", binary, ".")
+	build.Dir = tempDir
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Generated code does not compile: %v\n%s\n%s", err, out, source)
+	}
+
+	run := func(env ...string) (string, bool) {
+		cmd := exec.Command(binary)
+		cmd.Env = append([]string{"PATH=" + os.Getenv("PATH")}, env...)
+		out, err := cmd.CombinedOutput()
+		return strings.TrimSpace(string(out)), err == nil
+	}
+
+	out, ok := run("API_URL=https://api.example.com", "DEBUG=true")
+	if !ok || out != "https://api.example.com 8080 true info sk_test" {
+		t.Errorf("Unexpected result of Load: %q", out)
+	}
+
+	out, ok = run("PORT=70000", "LOG_LEVEL=verbose", "STRIPE_KEY=pk_live")
+	if ok {
+		t.Fatalf("Expected Load to fail, got %q", out)
+	}
+	for _, expected := range []string{
+		"API_URL is required",
+		"PORT must be of type port",
+		"LOG_LEVEL must be one of debug|info|warn",
+		"STRIPE_KEY must match ^sk_",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Expected %q in the error, got %q", expected, out)
+		}
+	}
+	if strings.Contains(out, "pk_live") {
+		t.Errorf("Expected errors to leave out values, got %q", out)
+	}
+}
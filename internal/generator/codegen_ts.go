@@ -0,0 +1,262 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Validator is a TypeScript validation library a schema module can be generated for
+type Validator string
+
+const (
+	// ValidatorZod generates a schema module for zod
+	ValidatorZod Validator = "zod"
+	// ValidatorValibot generates a schema module for valibot v1
+	ValidatorValibot Validator = "valibot"
+)
+
+// goDurationPattern matches the durations accepted by time.ParseDuration
+const goDurationPattern = `^[-+]?(0|((\d+(\.\d*)?|\.\d+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// goBoolPattern matches the spellings accepted by strconv.ParseBool
+const goBoolPattern = `^(1|t|T|TRUE|true|True|0|f|F|FALSE|false|False)$`
+
+// tsString quotes s as a TypeScript string literal
+func tsString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// tsProperty returns key as a property name, quoted when it isn't an identifier
+func tsProperty(key string) string {
+	if shellKeyRe.MatchString(key) {
+		return key
+	}
+	return tsString(key)
+}
+
+// tsDocComment writes a JSDoc comment with the comment group, type, default and secrecy of a field
+func tsDocComment(buf *bytes.Buffer, indent string, field *Field) {
+	var lines []string
+	lines = append(lines, field.Comments...)
+	if len(lines) > 0 {
+		lines = append(lines, "")
+	}
+	if field.Type != TypeString {
+		lines = append(lines, "@format "+string(field.Type))
+	}
+	if field.Default != "" {
+		lines = append(lines, "@default "+field.Default)
+	}
+	if field.Generated {
+		lines = append(lines, "@secret")
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Fprintf(buf, "%s/**\n", indent)
+	for _, line := range lines {
+		line = strings.ReplaceAll(line, "*/", `*\/`)
+		if line == "" {
+			fmt.Fprintf(buf, "%s *\n", indent)
+			continue
+		}
+		fmt.Fprintf(buf, "%s * %s\n", indent, line)
+	}
+	fmt.Fprintf(buf, "%s */\n", indent)
+}
+
+// TypeScriptDeclarations generates an env.d.ts that types process.env after the schema
+// Values stay strings as Node.js provides them; @enum narrows them to their literals
+// and keys without @required are optional.
+func (s *Schema) TypeScriptDeclarations() []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genenv from %s; DO NOT EDIT.\n\n", s.TemplatePath)
+	buf.WriteString("declare global {\n  namespace NodeJS {\n    interface ProcessEnv {\n")
+	for _, field := range s.Fields {
+		tsDocComment(&buf, "      ", field)
+
+		valueType := "string"
+		if field.Enum != nil {
+			literals := make([]string, len(field.Enum))
+			for i, value := range field.Enum {
+				literals[i] = tsString(value)
+			}
+			valueType = strings.Join(literals, " | ")
+		}
+		optional := "?"
+		if field.Required {
+			optional = ""
+		}
+		fmt.Fprintf(&buf, "      %s%s: %s;\n", tsProperty(field.Key), optional, valueType)
+	}
+	buf.WriteString("    }\n  }\n}\n\nexport {};\n")
+	return buf.Bytes()
+}
+
+// TypeScriptSchema generates a schema module for the validator with an envSchema that
+// checks the annotations and converts typed values, a parseEnv function, and the list
+// of keys whose values are generated secrets
+func (s *Schema) TypeScriptSchema(validator Validator) ([]byte, error) {
+	var schemaFor func(field *Field) string
+	var header, infer, parse string
+	switch validator {
+	case ValidatorZod:
+		schemaFor = zodSchema
+		header = `import { z } from "zod";`
+		infer = "z.infer<typeof envSchema>"
+		parse = "envSchema.parse(values)"
+	case ValidatorValibot:
+		schemaFor = valibotSchema
+		header = `import * as v from "valibot";`
+		infer = "v.InferOutput<typeof envSchema>"
+		parse = "v.parse(envSchema, values)"
+	default:
+		return nil, fmt.Errorf("unknown validator %q; use zod or valibot", validator)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by genenv from %s; DO NOT EDIT.\n\n", s.TemplatePath)
+	fmt.Fprintf(&buf, "%s\n\n", header)
+
+	if validator == ValidatorZod {
+		buf.WriteString("export const envSchema = z.object({\n")
+	} else {
+		buf.WriteString("export const envSchema = v.object({\n")
+	}
+	for _, field := range s.Fields {
+		tsDocComment(&buf, "  ", field)
+		fmt.Fprintf(&buf, "  %s: %s,\n", tsProperty(field.Key), schemaFor(field))
+	}
+	buf.WriteString("});\n\n")
+
+	fmt.Fprintf(&buf, "export type Env = %s;\n\n", infer)
+
+	var secrets []string
+	for _, field := range s.Fields {
+		if field.Generated {
+			secrets = append(secrets, tsString(field.Key))
+		}
+	}
+	buf.WriteString("/** Keys whose values are generated secrets and must not be logged */\n")
+	fmt.Fprintf(&buf, "export const secretKeys = [%s] as const;\n\n", strings.Join(secrets, ", "))
+
+	buf.WriteString("/**\n * Parses and validates environment variables. Empty values count as unset,\n")
+	buf.WriteString(" * so they fall back to the template value like in genenv check.\n */\n")
+	buf.WriteString("export function parseEnv(env: Record<string, string | undefined> = process.env): Env {\n")
+	buf.WriteString("  const values = Object.fromEntries(Object.entries(env).filter(([, value]) => value !== undefined && value !== \"\"));\n")
+	fmt.Fprintf(&buf, "  return %s;\n}\n", parse)
+
+	return buf.Bytes(), nil
+}
+
+// zodSchema returns the zod schema of a field
+func zodSchema(field *Field) string {
+	var schema string
+	if field.Enum != nil {
+		values := make([]string, len(field.Enum))
+		for i, value := range field.Enum {
+			values[i] = tsString(value)
+		}
+		schema = "z.enum([" + strings.Join(values, ", ") + "])"
+	} else {
+		schema = "z.string()"
+		switch field.Type {
+		case TypeURL:
+			schema += ".url()"
+		case TypeEmail:
+			schema += ".email()"
+		case TypeDuration:
+			schema += ".regex(new RegExp(" + tsString(goDurationPattern) + "))"
+		case TypeBool:
+			schema += ".regex(new RegExp(" + tsString(goBoolPattern) + "))"
+		}
+	}
+	if field.Pattern != nil {
+		schema += ".regex(new RegExp(" + tsString(field.Pattern.String()) + "))"
+	}
+
+	// The default goes before the conversion so it is converted like any other value
+	if field.Default != "" {
+		schema += ".default(" + tsString(field.Default) + ")"
+	}
+
+	if field.Enum == nil {
+		switch field.Type {
+		case TypeInt:
+			schema += ".pipe(z.coerce.number().int())"
+		case TypeFloat:
+			schema += ".pipe(z.coerce.number())"
+		case TypePort:
+			schema += ".pipe(z.coerce.number().int().min(1).max(65535))"
+		case TypeBool:
+			schema += `.transform((value) => /^(1|t|true)$/i.test(value))`
+		}
+	}
+
+	if !field.Required && field.Default == "" {
+		schema += ".optional()"
+	}
+	return schema
+}
+
+// valibotSchema returns the valibot schema of a field
+func valibotSchema(field *Field) string {
+	var actions []string
+	if field.Enum != nil {
+		values := make([]string, len(field.Enum))
+		for i, value := range field.Enum {
+			values[i] = tsString(value)
+		}
+		actions = append(actions, "v.picklist(["+strings.Join(values, ", ")+"])")
+	} else {
+		actions = append(actions, "v.string()")
+		switch field.Type {
+		case TypeURL:
+			actions = append(actions, "v.url()")
+		case TypeEmail:
+			actions = append(actions, "v.email()")
+		case TypeDuration:
+			actions = append(actions, "v.regex(new RegExp("+tsString(goDurationPattern)+"))")
+		case TypeBool:
+			actions = append(actions, "v.regex(new RegExp("+tsString(goBoolPattern)+"))")
+		}
+	}
+	if field.Pattern != nil {
+		actions = append(actions, "v.regex(new RegExp("+tsString(field.Pattern.String())+"))")
+	}
+	if field.Enum == nil {
+		switch field.Type {
+		case TypeInt:
+			actions = append(actions, "v.transform(Number)", "v.integer()")
+		case TypeFloat:
+			actions = append(actions, "v.transform(Number)", "v.number()")
+		case TypePort:
+			actions = append(actions, "v.transform(Number)", "v.integer()", "v.minValue(1)", "v.maxValue(65535)")
+		case TypeBool:
+			actions = append(actions, `v.transform((value) => /^(1|t|true)$/i.test(value))`)
+		}
+	}
+
+	schema := actions[0]
+	if len(actions) > 1 {
+		schema = "v.pipe(" + strings.Join(actions, ", ") + ")"
+	}
+
+	switch {
+	case field.Default != "":
+		return "v.optional(" + schema + ", " + tsString(field.Default) + ")"
+	case !field.Required:
+		return "v.optional(" + schema + ")"
+	}
+	return schema
+}
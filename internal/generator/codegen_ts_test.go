@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"strings"
+	"testing"
+)
+
+// tsTestTemplate declares a key of every kind the TypeScript generators handle
+const tsTestTemplate = `# Public URL of the API
+# @type url
+# @required
+API_URL=
+
+# @type port
+PORT=8080
+
+# @enum debug|info|warn
+LOG_LEVEL=info
+
+SESSION_SECRET=${session_secret}
+`
+
+// TestSchemaTypeScriptDeclarations tests the generated process.env typings
+func TestSchemaTypeScriptDeclarations(t *testing.T) {
+	schema, err := parseSchema(".env.example", strings.Split(tsTestTemplate, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+	code := string(schema.TypeScriptDeclarations())
+
+	for _, expected := range []string{
+		"// Code generated by genenv from .env.example; DO NOT EDIT.",
+		"interface ProcessEnv {",
+		"       * Public URL of the API\n       *\n       * @format url\n       */\n      API_URL: string;",
+		"       * @default 8080\n       */\n      PORT?: string;",
+		`LOG_LEVEL?: "debug" | "info" | "warn";`,
+		"       * @secret\n       */\n      SESSION_SECRET?: string;",
+		"export {};",
+	} {
+		if !strings.Contains(code, expected) {
+			t.Errorf("Declarations do not contain %q:\n%s", expected, code)
+		}
+	}
+	if string(schema.TypeScriptDeclarations()) != code {
+		t.Error("Declarations are not deterministic")
+	}
+}
+
+// TestSchemaTypeScriptSchema tests the generated zod and valibot schema modules
+func TestSchemaTypeScriptSchema(t *testing.T) {
+	schema, err := parseSchema(".env.example", strings.Split(tsTestTemplate, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	tests := map[Validator][]string{
+		ValidatorZod: {
+			`import { z } from "zod";`,
+			"API_URL: z.string().url(),",
+			`PORT: z.string().default("8080").pipe(z.coerce.number().int().min(1).max(65535)),`,
+			`LOG_LEVEL: z.enum(["debug", "info", "warn"]).default("info"),`,
+			"SESSION_SECRET: z.string().optional(),",
+			"export type Env = z.infer<typeof envSchema>;",
+		},
+		ValidatorValibot: {
+			`import * as v from "valibot";`,
+			"API_URL: v.pipe(v.string(), v.url()),",
+			`PORT: v.optional(v.pipe(v.string(), v.transform(Number), v.integer(), v.minValue(1), v.maxValue(65535)), "8080"),`,
+			`LOG_LEVEL: v.optional(v.picklist(["debug", "info", "warn"]), "info"),`,
+			"SESSION_SECRET: v.optional(v.string()),",
+			"export type Env = v.InferOutput<typeof envSchema>;",
+		},
+	}
+	for validator, expectations := range tests {
+		source, err := schema.TypeScriptSchema(validator)
+		if err != nil {
+			t.Fatalf("Failed to generate %s schema: %v", validator, err)
+		}
+		code := string(source)
+		for _, expected := range append(expectations, `export const secretKeys = ["SESSION_SECRET"] as const;`) {
+			if !strings.Contains(code, expected) {
+				t.Errorf("%s schema does not contain %q:\n%s", validator, expected, code)
+			}
+		}
+	}
+
+	if _, err := schema.TypeScriptSchema("yup"); err == nil {
+		t.Error("Expected an error for an unknown validator")
+	}
+}
@@ -0,0 +1,206 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Directives that keep or drop template lines depending on a condition
+const (
+	// ifDirective starts a block kept only when its condition holds, e.g. # @if STORAGE == "s3"
+	ifDirective = "if"
+	// elseDirective starts the lines kept when the condition of the block doesn't hold
+	elseDirective = "else"
+	// endifDirective ends a conditional block
+	endifDirective = "endif"
+)
+
+// envConditionPrefix makes a condition read the process environment, e.g. # @if env.CI
+const envConditionPrefix = "env."
+
+// conditionRe matches a condition: an optionally negated operand on its own, or an operand
+// compared with == or != to a quoted or bare value
+var conditionRe = regexp.MustCompile(`^(!?)\s*((?:env\.)?[A-Za-z_][A-Za-z0-9_]*)\s*(?:(==|!=)\s*("[^"]*"|'[^']*'|[^\s"']+))?$`)
+
+// falseConditionValues are the values that don't satisfy a condition without comparison,
+// along with the empty value
+var falseConditionValues = []string{"0", "false", "no", "off"}
+
+// condition is a parsed @if condition
+type condition struct {
+	negate   bool
+	operand  string // Template key or env.NAME
+	operator string // == or !=, empty to test the operand alone
+	value    string
+}
+
+// parseCondition parses the condition of an @if directive
+func parseCondition(expr string) (condition, error) {
+	if expr == "" {
+		return condition{}, fmt.Errorf("@%s needs a condition", ifDirective)
+	}
+	match := conditionRe.FindStringSubmatch(expr)
+	if match == nil {
+		return condition{}, fmt.Errorf("invalid condition %q; use KEY, !KEY, KEY == \"value\" or KEY != \"value\", with env.NAME for the environment", expr)
+	}
+	c := condition{negate: match[1] == "!", operand: match[2], operator: match[3], value: unquoteValue(match[4])}
+	if c.negate && c.operator != "" {
+		return condition{}, fmt.Errorf("invalid condition %q; negate a comparison with != instead", expr)
+	}
+	return c, nil
+}
+
+// keyLookup returns the value of a key defined above a line of a template
+type keyLookup func(key string) (string, bool)
+
+// evaluate checks if the condition holds
+// Keys must be defined above the condition.
+func (g *Generator) evaluate(c condition, defined keyLookup) (bool, error) {
+	var value string
+	if name, ok := strings.CutPrefix(c.operand, envConditionPrefix); ok {
+		value = os.Getenv(name)
+	} else {
+		if value, ok = g.keyValue(c.operand, defined); !ok {
+			return false, fmt.Errorf("condition refers to %s, which is not defined above it", c.operand)
+		}
+	}
+
+	var holds bool
+	switch c.operator {
+	case "==":
+		holds = value == c.value
+	case "!=":
+		holds = value != c.value
+	default:
+		holds = value != ""
+		for _, falseValue := range falseConditionValues {
+			if strings.EqualFold(value, falseValue) {
+				holds = false
+			}
+		}
+	}
+	return holds != c.negate, nil
+}
+
+// keyValue returns the value of a key defined above a template line
+// Keys take their value in the existing output, so a value the user changed there decides,
+// and otherwise their template value.
+func (g *Generator) keyValue(key string, defined keyLookup) (string, bool) {
+	value, ok := defined(key)
+	if !ok {
+		return "", false
+	}
+	if outputValue, ok := g.outputValue(key); ok {
+		return outputValue, true
+	}
+	return value, true
+}
+
+// outputValue returns the value of a key in the existing output file
+// Generate sets the values from the output it reads under the output lock, so conditions
+// agree with the file it merges into. Otherwise the output is read once; a missing or
+// unreadable output has no values.
+func (g *Generator) outputValue(key string) (string, bool) {
+	if g.outputValues == nil {
+		lines, _, _ := g.readOutputFile()
+		g.setOutputValues(lines)
+	}
+	value, ok := g.outputValues[key]
+	return value, ok
+}
+
+// setOutputValues records the values of the existing output that template conditions read
+func (g *Generator) setOutputValues(lines []EnvLine) {
+	g.outputValues = make(map[string]string)
+	for _, line := range lines {
+		if line.Type == LineTypeKeyValue {
+			g.outputValues[line.Key] = unquoteValue(line.Value)
+		}
+	}
+}
+
+// conditionalBlock is an @if block open while a template is read
+type conditionalBlock struct {
+	source   lineSource
+	enclosed bool // Whether the lines around the block are kept
+	holds    bool // Whether the condition holds
+	inElse   bool
+}
+
+// conditionals tracks the @if blocks of a template while its lines are read
+type conditionals struct {
+	blocks []conditionalBlock
+}
+
+// active checks if the lines at the current position are kept
+func (c *conditionals) active() bool {
+	if len(c.blocks) == 0 {
+		return true
+	}
+	block := c.blocks[len(c.blocks)-1]
+	return block.enclosed && block.holds != block.inElse
+}
+
+// handle processes a conditional directive and reports whether the line was one
+// Conditions of blocks inside dropped lines are checked for syntax but not evaluated.
+func (c *conditionals) handle(g *Generator, name, arg string, source lineSource, defined keyLookup) (bool, error) {
+	switch name {
+	case ifDirective:
+		cond, err := parseCondition(arg)
+		if err != nil {
+			return true, err
+		}
+		block := conditionalBlock{source: source, enclosed: c.active()}
+		if block.enclosed {
+			if block.holds, err = g.evaluate(cond, defined); err != nil {
+				return true, err
+			}
+		}
+		c.blocks = append(c.blocks, block)
+		return true, nil
+
+	case elseDirective, endifDirective:
+		if arg != "" {
+			return true, fmt.Errorf("@%s takes no condition", name)
+		}
+		if len(c.blocks) == 0 {
+			return true, fmt.Errorf("@%s without a matching @%s", name, ifDirective)
+		}
+		top := &c.blocks[len(c.blocks)-1]
+		if name == endifDirective {
+			c.blocks = c.blocks[:len(c.blocks)-1]
+			return true, nil
+		}
+		if top.inElse {
+			return true, fmt.Errorf("@%s at %s already has an @%s", ifDirective, top.source, elseDirective)
+		}
+		top.inElse = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// close reports an @if block left open at the end of the template
+func (c *conditionals) close() error {
+	if len(c.blocks) == 0 {
+		return nil
+	}
+	block := c.blocks[len(c.blocks)-1]
+	return fmt.Errorf("%s: @%s is never closed with @%s", block.source, ifDirective, endifDirective)
+}
+
+// layerLookup looks up the template value of the last definition of a key in layers,
+// searching them in order
+func layerLookup(layers ...*templateLayer) keyLookup {
+	return func(key string) (string, bool) {
+		for _, layer := range layers {
+			if index := lastKeyIndex(layer.lines, key); index >= 0 {
+				_, value, _ := parseKeyValue(layer.lines[index])
+				return strings.ReplaceAll(unquoteValue(value), `\${`, "${"), true
+			}
+		}
+		return "", false
+	}
+}
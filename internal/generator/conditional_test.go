@@ -0,0 +1,182 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseCondition tests parsing @if conditions
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected condition
+		wantErr  bool
+	}{
+		{expr: `STORAGE == "s3"`, expected: condition{operand: "STORAGE", operator: "==", value: "s3"}},
+		{expr: `STORAGE != 'local'`, expected: condition{operand: "STORAGE", operator: "!=", value: "local"}},
+		{expr: `LOG_LEVEL==debug`, expected: condition{operand: "LOG_LEVEL", operator: "==", value: "debug"}},
+		{expr: `env.CI`, expected: condition{operand: "env.CI"}},
+		{expr: `!env.CI`, expected: condition{negate: true, operand: "env.CI"}},
+		{expr: ``, wantErr: true},
+		{expr: `STORAGE = "s3"`, wantErr: true},
+		{expr: `STORAGE == "s3" extra`, wantErr: true},
+		{expr: `!STORAGE == "s3"`, wantErr: true},
+		{expr: `1KEY`, wantErr: true},
+	}
+
+	for _, test := range tests {
+		c, err := parseCondition(test.expr)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseCondition(%q) should fail, got %+v", test.expr, c)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseCondition(%q) failed: %v", test.expr, err)
+			continue
+		}
+		if c != test.expected {
+			t.Errorf("parseCondition(%q) = %+v, expected %+v", test.expr, c, test.expected)
+		}
+	}
+}
+
+// TestConditionalBlocks tests that only the active branches of @if blocks are generated
+func TestConditionalBlocks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	t.Setenv("GENENV_TEST_CI", "true")
+	template := strings.Join([]string{
+		"STORAGE=local",
+		"",
+		"# @if STORAGE == \"s3\"",
+		"# S3 bucket",
+		"S3_BUCKET=uploads",
+		"S3_SECRET=${s3_secret}",
+		"# @else",
+		"UPLOAD_DIR=./uploads",
+		"# @endif",
+		"",
+		"# @if env.GENENV_TEST_CI",
+		"CI_TOKEN=${ci_token}",
+		"# @if !env.GENENV_TEST_UNSET",
+		"CI_NESTED=yes",
+		"# @endif",
+		"# @endif",
+		"",
+	}, "\n")
+	templatePath := filepath.Join(tempDir, ".env.example")
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	config := Config{TemplatePath: templatePath, OutputPath: outputPath}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	env := readEnv(t, outputPath)
+	for _, key := range []string{"STORAGE", "UPLOAD_DIR", "CI_TOKEN", "CI_NESTED"} {
+		if _, ok := env[key]; !ok {
+			t.Errorf("Expected %s in output, got %v", key, env)
+		}
+	}
+	for _, key := range []string{"S3_BUCKET", "S3_SECRET"} {
+		if _, ok := env[key]; ok {
+			t.Errorf("Expected %s to be left out, got %v", key, env)
+		}
+	}
+	if data, _ := os.ReadFile(outputPath); strings.Contains(string(data), "@if") || strings.Contains(string(data), "\n\n\n") {
+		t.Errorf("Directives or doubled blank lines left in output:\n%s", data)
+	}
+
+	// Switching the storage in .env brings in the S3 keys as missing keys
+	// The generator has already seen the old storage; the conditions must use the output
+	// read under the lock, not what was read before it
+	g := New(config)
+	if value, _ := g.outputValue("STORAGE"); value != "local" {
+		t.Fatalf("Expected the storage of the first run, got %q", value)
+	}
+	data, _ := os.ReadFile(outputPath)
+	os.WriteFile(outputPath, []byte(strings.Replace(string(data), "STORAGE=local", "STORAGE=s3", 1)), 0600)
+	if err := g.Generate(); err != nil {
+		t.Fatalf("Failed to re-generate .env file: %v", err)
+	}
+	updated, _ := os.ReadFile(outputPath)
+	env = readEnv(t, outputPath)
+	if env["S3_BUCKET"] != "uploads" || len(env["S3_SECRET"]) != DefaultValueLength {
+		t.Errorf("Expected S3 keys to be added, got %v", env)
+	}
+	if !strings.Contains(string(updated), "# S3 bucket\nS3_BUCKET=uploads") {
+		t.Errorf("Expected the comment group of S3_BUCKET to be added:\n%s", updated)
+	}
+	if _, ok := env["UPLOAD_DIR"]; !ok {
+		t.Errorf("Existing keys of the inactive branch should be kept, got %v", env)
+	}
+}
+
+// TestConditionalErrors tests line-numbered errors for malformed conditionals
+func TestConditionalErrors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"unclosed", "A=1\n# @if A\nB=2\n", ".env.example:2: @if is never closed with @endif"},
+		{"stray endif", "A=1\n# @endif\n", ".env.example:2: @endif without a matching @if"},
+		{"stray else", "# @else\n", ".env.example:1: @else without a matching @if"},
+		{"double else", "A=1\n# @if A\n# @else\n# @else\n# @endif\n", ".env.example:4: @if at"},
+		{"missing condition", "# @if\n# @endif\n", ".env.example:1: @if needs a condition"},
+		{"bad condition", "A=1\n# @if A = 1\n# @endif\n", ".env.example:2: invalid condition"},
+		{"undefined key", "# @if STORAGE == \"s3\"\n# @endif\nSTORAGE=s3\n", ".env.example:1: condition refers to STORAGE"},
+		{"endif argument", "A=1\n# @if A\n# @endif A\n", ".env.example:3: @endif takes no condition"},
+		{"nested unchecked syntax", "A=\n# @if A\n# @if B = 1\n# @endif\n# @endif\n", ".env.example:3: invalid condition"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			templatePath := filepath.Join(tempDir, ".env.example")
+			if err := os.WriteFile(templatePath, []byte(test.template), 0644); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+			err := New(Config{TemplatePath: templatePath, OutputPath: filepath.Join(tempDir, ".env")}).Generate()
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+// TestConditionalInclude tests that conditions see the keys of the including template
+func TestConditionalInclude(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"s3.example", "# @if STORAGE == \"s3\"\nS3_BUCKET=uploads\n# @endif\n"},
+		[2]string{".env.example", "STORAGE=s3\n# @include s3.example\n# @if STORAGE == \"gcs\"\n# @include missing.example\n# @endif\n"},
+	)
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := New(Config{TemplatePath: paths[1], OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	if env := readEnv(t, outputPath); env["S3_BUCKET"] != "uploads" {
+		t.Errorf("Expected S3_BUCKET from the included template, got %v", env)
+	}
+}
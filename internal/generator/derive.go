@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultDeriveNamespace is the namespace used for derived values when none is given
+	DefaultDeriveNamespace = "default"
+	// DefaultDeriveVersion is the version label used for derived values when none is given
+	DefaultDeriveVersion = "v1"
+
+	// MinMasterKeyLength is the minimum accepted length of a master key in bytes
+	MinMasterKeyLength = 16
+
+	// deriveHeaderPrefix marks the comment line that records the derivation context
+	deriveHeaderPrefix = "# genenv:derive "
+)
+
+// ReadMasterKey reads the master key from a file or, if path is empty, from an environment variable
+// Surrounding whitespace is trimmed so keys written with a trailing newline work as expected
+func ReadMasterKey(path, envVar string) ([]byte, error) {
+	var key string
+	switch {
+	case path != "":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read master key file: %w", err)
+		}
+		key = string(data)
+	case envVar != "":
+		value, ok := os.LookupEnv(envVar)
+		if !ok {
+			return nil, fmt.Errorf("master key environment variable %s is not set", envVar)
+		}
+		key = value
+	default:
+		return nil, fmt.Errorf("no master key source given")
+	}
+
+	key = strings.TrimSpace(key)
+	if len(key) < MinMasterKeyLength {
+		return nil, fmt.Errorf("master key must be at least %d bytes", MinMasterKeyLength)
+	}
+
+	return []byte(key), nil
+}
+
+// deriveBytes derives length bytes for a placeholder from the master key
+// The HKDF info binds the namespace, version label, placeholder name and generator spec,
+// so changing any of them yields an unrelated value
+func (g *Generator) deriveBytes(placeholderName, spec string, length int) ([]byte, error) {
+	info := strings.Join([]string{
+		g.config.DeriveNamespace,
+		g.config.DeriveVersion,
+		placeholderName,
+		spec,
+	}, "\x00")
+
+	derived, err := hkdf.Key(sha256.New, g.config.MasterKey, nil, info, length)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive value for %s: %w", placeholderName, err)
+	}
+
+	return derived, nil
+}
+
+// generatorSpec returns a canonical description of how a value is generated
+func generatorSpec(charset CharsetType, length int) string {
+	return fmt.Sprintf("charset=%s,length=%d", charset, length)
+}
+
+// deriveHeader returns the comment line recording the current derivation context
+func (g *Generator) deriveHeader() string {
+	return fmt.Sprintf("%snamespace=%s version=%s", deriveHeaderPrefix, g.config.DeriveNamespace, g.config.DeriveVersion)
+}
+
+// isDeriveHeader checks if a line records a derivation context
+func isDeriveHeader(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), strings.TrimSpace(deriveHeaderPrefix))
+}
+
+// applyDeriveHeader updates the derivation context recorded in existing output lines
+// Without --force a changed context is an error, since the stored values would no longer
+// match what the new context derives; with --force the header is rewritten to match
+func (g *Generator) applyDeriveHeader(lines []string) ([]string, error) {
+	headerIndex := -1
+	for i, line := range lines {
+		if isDeriveHeader(line) {
+			headerIndex = i
+			break
+		}
+	}
+
+	derived := g.config.MasterKey != nil
+
+	if !g.config.Force {
+		if derived && headerIndex != -1 && strings.TrimSpace(lines[headerIndex]) != g.deriveHeader() {
+			return nil, fmt.Errorf("derivation context changed (%s); rerun with --force to rotate derived values",
+				strings.TrimPrefix(strings.TrimSpace(lines[headerIndex]), strings.TrimSpace(deriveHeaderPrefix)+" "))
+		}
+		return lines, nil
+	}
+
+	switch {
+	case derived && headerIndex != -1:
+		lines[headerIndex] = g.deriveHeader()
+	case derived:
+		lines = append([]string{g.deriveHeader()}, lines...)
+	case headerIndex != -1:
+		// Values were regenerated randomly, so the recorded context no longer applies
+		lines = append(lines[:headerIndex], lines[headerIndex+1:]...)
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,204 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMasterKey = "0123456789abcdef0123456789abcdef"
+
+// TestGeneratorDerivedValuesAreReproducible tests that the same master key yields the same values
+func TestGeneratorDerivedValuesAreReproducible(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `DB_PASSWORD=${db_password}
+API_KEY=${api_key}`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	// Simulate two hosts generating their own .env files
+	var results []map[string]string
+	for _, name := range []string{"api.env", "worker.env"} {
+		config := Config{
+			TemplatePath: templatePath,
+			OutputPath:   filepath.Join(tempDir, name),
+			MasterKey:    []byte(testMasterKey),
+		}
+		if err := New(config).Generate(); err != nil {
+			t.Fatalf("Failed to generate %s: %v", name, err)
+		}
+
+		content, err := os.ReadFile(config.OutputPath)
+		if err != nil {
+			t.Fatalf("Failed to read generated file: %v", err)
+		}
+		if !strings.HasPrefix(string(content), "# genenv:derive namespace=default version=v1\n") {
+			t.Errorf("Derivation context was not recorded, got:\n%s", content)
+		}
+		results = append(results, parseEnvFile(string(content)))
+	}
+
+	if results[0]["DB_PASSWORD"] != results[1]["DB_PASSWORD"] {
+		t.Errorf("Derived values differ between runs: %s != %s", results[0]["DB_PASSWORD"], results[1]["DB_PASSWORD"])
+	}
+	if results[0]["DB_PASSWORD"] == results[0]["API_KEY"] {
+		t.Error("Different placeholders should derive different values")
+	}
+	if len(results[0]["DB_PASSWORD"]) != DefaultValueLength {
+		t.Errorf("Derived value has wrong length: %s", results[0]["DB_PASSWORD"])
+	}
+}
+
+// TestGeneratorDerivedValuesDependOnContext tests that namespace, version and spec change the value
+func TestGeneratorDerivedValuesDependOnContext(t *testing.T) {
+	base := Config{MasterKey: []byte(testMasterKey)}
+	baseValue, err := New(base).generateSecureValue("secret")
+	if err != nil {
+		t.Fatalf("Failed to derive value: %v", err)
+	}
+
+	variants := map[string]Config{
+		"namespace": {MasterKey: []byte(testMasterKey), DeriveNamespace: "other"},
+		"version":   {MasterKey: []byte(testMasterKey), DeriveVersion: "v2"},
+		"charset":   {MasterKey: []byte(testMasterKey), Charset: CharsetLowercase},
+		"key":       {MasterKey: []byte("fedcba9876543210fedcba9876543210")},
+	}
+	for name, config := range variants {
+		value, err := New(config).generateSecureValue("secret")
+		if err != nil {
+			t.Fatalf("Failed to derive value for %s variant: %v", name, err)
+		}
+		if value == baseValue {
+			t.Errorf("Changing the %s should change the derived value", name)
+		}
+	}
+
+	// Different placeholder names must not collide
+	otherValue, err := New(base).generateSecureValue("other_secret")
+	if err != nil {
+		t.Fatalf("Failed to derive value: %v", err)
+	}
+	if otherValue == baseValue {
+		t.Error("Different placeholder names should derive different values")
+	}
+}
+
+// TestGeneratorDerivedVersionBumpRequiresForce tests that rotating the version label needs --force
+func TestGeneratorDerivedVersionBumpRequiresForce(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("SECRET=${secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	config := Config{
+		TemplatePath: templatePath,
+		OutputPath:   outputPath,
+		MasterKey:    []byte(testMasterKey),
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	first, _ := os.ReadFile(outputPath)
+
+	// Bumping the version without --force must not silently keep stale values
+	config.DeriveVersion = "v2"
+	if err := New(config).Generate(); err == nil {
+		t.Fatal("Generate should fail when the derivation context changes without --force")
+	}
+
+	config.Force = true
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to rotate derived values: %v", err)
+	}
+	second, _ := os.ReadFile(outputPath)
+
+	if !strings.Contains(string(second), "# genenv:derive namespace=default version=v2") {
+		t.Errorf("Derivation header was not updated, got:\n%s", second)
+	}
+	if parseEnvFile(string(first))["SECRET"] == parseEnvFile(string(second))["SECRET"] {
+		t.Error("SECRET was not rotated after bumping the version")
+	}
+}
+
+// TestReadMasterKey tests reading the master key from a file and an environment variable
+func TestReadMasterKey(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	keyPath := filepath.Join(tempDir, "master.key")
+	if err := os.WriteFile(keyPath, []byte(testMasterKey+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+
+	key, err := ReadMasterKey(keyPath, "")
+	if err != nil {
+		t.Fatalf("Failed to read master key file: %v", err)
+	}
+	if string(key) != testMasterKey {
+		t.Errorf("Trailing newline was not trimmed: %q", key)
+	}
+
+	t.Setenv("GENENV_TEST_MASTER_KEY", testMasterKey)
+	if _, err := ReadMasterKey("", "GENENV_TEST_MASTER_KEY"); err != nil {
+		t.Errorf("Failed to read master key from environment: %v", err)
+	}
+
+	if _, err := ReadMasterKey("", "GENENV_TEST_UNSET_KEY"); err == nil {
+		t.Error("ReadMasterKey should fail for an unset environment variable")
+	}
+
+	t.Setenv("GENENV_TEST_SHORT_KEY", "short")
+	if _, err := ReadMasterKey("", "GENENV_TEST_SHORT_KEY"); err == nil {
+		t.Error("ReadMasterKey should reject short keys")
+	}
+}
+
+// TestGeneratorDerivedValuesNeedDotenv tests that derivation is refused for formats that would
+// drop the derivation context
+func TestGeneratorDerivedValuesNeedDotenv(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("SECRET=${secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatK8sSecret, FormatShell} {
+		outputPath := filepath.Join(tempDir, "out."+string(format))
+		config := Config{
+			TemplatePath: templatePath,
+			OutputPath:   outputPath,
+			Format:       format,
+			MasterKey:    []byte(testMasterKey),
+		}
+		err := New(config).Generate()
+		if err == nil || !strings.Contains(err.Error(), "need the dotenv format") {
+			t.Errorf("Expected %s output of derived values to be refused, got %v", format, err)
+		}
+		if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s output to be written", format)
+		}
+	}
+}
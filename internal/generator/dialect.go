@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect selects which consumer's rules values in a dotenv file are written for
+type Dialect string
+
+const (
+	// DialectDotenv writes values as the template spells them, for dotenv libraries
+	DialectDotenv Dialect = "dotenv"
+	// DialectDocker writes values for docker run --env-file, which keeps quotes and comments literally
+	DialectDocker Dialect = "docker"
+	// DialectCompose writes values for Compose env_file, which interpolates $ unless escaped as $$
+	DialectCompose Dialect = "compose"
+	// DialectSystemd writes values for a systemd EnvironmentFile
+	DialectSystemd Dialect = "systemd"
+)
+
+// dialect returns the configured dialect, validating that it applies to the output format
+func (g *Generator) dialect() (Dialect, error) {
+	switch g.config.Dialect {
+	case "", DialectDotenv:
+		return DialectDotenv, nil
+	case DialectDocker, DialectCompose, DialectSystemd:
+	default:
+		return "", fmt.Errorf("unknown dialect: %s", g.config.Dialect)
+	}
+
+	if g.config.Format != "" && g.config.Format != FormatDotenv {
+		return "", fmt.Errorf("dialect %s only applies to the dotenv format", g.config.Dialect)
+	}
+	return g.config.Dialect, nil
+}
+
+// encodeValue formats the value a dotenv library would read from rawValue for the dialect
+// The dotenv dialect keeps rawValue exactly as written in the template.
+func encodeValue(key, rawValue string, dialect Dialect) (string, error) {
+	if dialect == DialectDotenv || dialect == "" {
+		return rawValue, nil
+	}
+
+	value := unquoteValue(rawValue)
+	switch dialect {
+	case DialectDocker:
+		// Docker takes everything after = literally and has no way to continue a line
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("value of %s spans several lines, which a docker env file cannot hold", key)
+		}
+		return value, nil
+	case DialectCompose:
+		value = strings.ReplaceAll(value, "$", "$$")
+		if !needsQuoting(value) {
+			return value, nil
+		}
+		replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+		return `"` + replacer.Replace(value) + `"`, nil
+	case DialectSystemd:
+		// Double quotes in an EnvironmentFile only escape \ and ", and a newline continues the value
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("value of %s spans several lines, which a systemd EnvironmentFile cannot hold on one line", key)
+		}
+		if !needsQuoting(value) {
+			return value, nil
+		}
+		replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		return `"` + replacer.Replace(value) + `"`, nil
+	}
+	return "", fmt.Errorf("unknown dialect: %s", dialect)
+}
+
+// needsQuoting checks if an unquoted value would be altered by quote, comment or whitespace handling
+func needsQuoting(value string) bool {
+	return value != strings.TrimSpace(value) || strings.ContainsAny(value, "\"'#\\\r\n")
+}
+
+// readValue returns the value the dialect's consumer reads from rawValue
+// ok is false when the value depends on the environment, i.e. Compose would interpolate it.
+func readValue(rawValue string, dialect Dialect) (value string, ok bool) {
+	switch dialect {
+	case DialectDocker:
+		return rawValue, true
+	case DialectCompose:
+		trimmed := strings.TrimSpace(rawValue)
+		if strings.HasPrefix(trimmed, "'") {
+			return unquoteValue(rawValue), true
+		}
+		// $$ is how Compose files spell a literal $, so only a bare $ is reported
+		if strings.Contains(strings.ReplaceAll(trimmed, "$$", ""), "$") {
+			return "", false
+		}
+		return unquoteValue(rawValue), true
+	case DialectSystemd:
+		return readSystemdValue(rawValue), true
+	default:
+		return unquoteValue(rawValue), true
+	}
+}
+
+// readSystemdValue follows systemd's EnvironmentFile parsing for a single-line value
+// There are no inline comments, and backslashes escape the next character outside single quotes.
+func readSystemdValue(rawValue string) string {
+	value := strings.TrimSpace(rawValue)
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case c == quote:
+			quote = 0
+		case c == '\\' && quote != '\'' && i+1 < len(value):
+			next := value[i+1]
+			if quote == 0 || strings.IndexByte("\"\\`$", next) >= 0 {
+				i++
+				c = next
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// checkDialect warns about lines whose value the dialect's consumer reads differently
+// from a dotenv library, so values copied from the template or an older file don't surprise anyone.
+// Warnings name the key but never the value, which may be a secret.
+func (g *Generator) checkDialect(lines []string, dialect Dialect) {
+	if dialect == DialectDotenv {
+		return
+	}
+
+	for i, line := range lines {
+		envLine := parseEnvLine(line)
+		if envLine.Type != LineTypeKeyValue {
+			continue
+		}
+
+		actual, ok := readValue(envLine.Value, dialect)
+		switch {
+		case !ok:
+			g.warnf("%s:%d: %s contains $, which %s interpolates; escape it as $$", g.config.OutputPath, i+1, envLine.Key, dialect)
+		case actual != unquoteValue(envLine.Value):
+			g.warnf("%s:%d: %s is read differently by %s than by dotenv libraries; check its quoting", g.config.OutputPath, i+1, envLine.Key, dialect)
+		}
+	}
+}
+
+// warnf records a warning for the caller to show after Generate returns
+func (g *Generator) warnf(format string, args ...any) {
+	g.warnings = append(g.warnings, fmt.Sprintf(format, args...))
+}
+
+// Warnings returns the warnings collected by the last Generate call
+func (g *Generator) Warnings() []string {
+	return g.warnings
+}
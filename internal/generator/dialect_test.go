@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestReplaceValueInLineDialects tests how each dialect writes the value a dotenv library would read
+func TestReplaceValueInLineDialects(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		newValue string
+		want     string
+	}{
+		{DialectDotenv, `"pa$s word"`, `KEY="pa$s word"`},
+		{DialectDocker, `"pa$s word"`, `KEY=pa$s word`},
+		{DialectDocker, `abc # comment`, `KEY=abc`},
+		{DialectCompose, `pa$s`, `KEY=pa$$s`},
+		{DialectCompose, `"pa$s word"`, `KEY=pa$$s word`},
+		{DialectCompose, `"it's"`, `KEY="it's"`},
+		{DialectSystemd, `"say \"hi\""`, `KEY="say \"hi\""`},
+		{DialectSystemd, `plain`, `KEY=plain`},
+	}
+
+	for _, tt := range tests {
+		got, err := replaceValueInLine("KEY=${value}", tt.newValue, tt.dialect)
+		if err != nil {
+			t.Errorf("%s: unexpected error for %s: %v", tt.dialect, tt.newValue, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: replacing with %s gave %s, want %s", tt.dialect, tt.newValue, got, tt.want)
+		}
+	}
+
+	// Neither docker nor a single systemd line can hold a multi-line value
+	for _, dialect := range []Dialect{DialectDocker, DialectSystemd} {
+		if _, err := replaceValueInLine("KEY=${value}", `"a\nb"`, dialect); err == nil {
+			t.Errorf("%s: expected an error for a multi-line value", dialect)
+		}
+	}
+}
+
+// TestReplaceValueInLineDialectsRoundTrip tests that each consumer reads back the intended value
+func TestReplaceValueInLineDialectsRoundTrip(t *testing.T) {
+	values := []string{"plain", "with space", `"quoted"`, "it's", `back\slash`, "a # b", "$dollar"}
+
+	for _, dialect := range []Dialect{DialectDocker, DialectCompose, DialectSystemd} {
+		for _, value := range values {
+			line, err := replaceValueInLine("KEY=", quoteValue(value), dialect)
+			if err != nil {
+				t.Fatalf("%s: failed to encode %q: %v", dialect, value, err)
+			}
+			rawValue := strings.TrimPrefix(line, "KEY=")
+
+			got, _ := readValue(rawValue, dialect)
+			if dialect == DialectCompose {
+				got = strings.ReplaceAll(got, "$$", "$")
+			}
+			if got != value {
+				t.Errorf("%s: %q was written as %s and read back as %q", dialect, value, line, got)
+			}
+		}
+	}
+}
+
+// TestGeneratorDialectWarnings tests that lines read differently by the dialect are reported
+func TestGeneratorDialectWarnings(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `# Quoted literals are copied as-is
+GREETING="hello world"
+PRICE=$5
+PASSWORD=${password}
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	config := Config{
+		TemplatePath: templatePath,
+		OutputPath:   outputPath,
+		Dialect:      DialectDocker,
+	}
+	gen := New(config)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	warnings := gen.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], ":2: GREETING is read differently by docker") {
+		t.Errorf("Expected one warning about GREETING, got %q", warnings)
+	}
+	if len(warnings) == 1 && strings.Contains(warnings[0], "hello world") {
+		t.Errorf("Expected the warning to leave out the value, got %q", warnings[0])
+	}
+
+	// Compose interpolates the bare $ in PRICE
+	if err := os.Remove(outputPath); err != nil {
+		t.Fatalf("Failed to remove output file: %v", err)
+	}
+	config.Dialect = DialectCompose
+	gen = New(config)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	warnings = gen.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], ":3: PRICE contains $") {
+		t.Errorf("Expected one warning about PRICE, got %q", warnings)
+	}
+}
+
+// TestGeneratorDialectRequiresDotenvFormat tests that a dialect can't be combined with other formats
+func TestGeneratorDialectRequiresDotenvFormat(t *testing.T) {
+	config := Config{
+		TemplatePath: "unused",
+		OutputPath:   "unused",
+		Format:       FormatJSON,
+		Dialect:      DialectDocker,
+	}
+	if err := New(config).Generate(); err == nil {
+		t.Error("Generate should reject a dialect for the JSON format")
+	}
+}
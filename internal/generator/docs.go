@@ -0,0 +1,293 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+)
+
+// DocsFormat is the markup of generated environment variable documentation
+type DocsFormat string
+
+const (
+	// DocsMarkdown renders a GitHub-flavored Markdown table
+	DocsMarkdown DocsFormat = "markdown"
+	// DocsHTML renders an HTML table
+	DocsHTML DocsFormat = "html"
+)
+
+// Markers of the region UpdateDocs replaces in an existing document
+const (
+	DocsStartMarker = "<!-- genenv:docs:start -->"
+	DocsEndMarker   = "<!-- genenv:docs:end -->"
+)
+
+// docsSecretValue replaces template literals that look like secrets
+const docsSecretValue = "(secret)"
+
+// DocEntry describes a template key in the documentation
+type DocEntry struct {
+	Key         string
+	Section     string
+	Description string
+	Default     string // Template value, with placeholders kept and literal secrets hidden
+	Generated   bool
+	Generator   string // How generated values are made, e.g. "random alphanumeric, 24 chars"
+}
+
+// DocEntries reads the template and describes each of its keys in order
+// A comment group heads a section when it stands alone above a blank line, or when it sits
+// on top of a block of keys; it then names the section rather than describing the first key.
+func (g *Generator) DocEntries() ([]DocEntry, error) {
+	lines, _, err := g.readTemplateFile()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := parseSchema(g.config.TemplatePath, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	sections, headings := docSections(lines)
+	entries := make([]DocEntry, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		_, value, _ := parseKeyValue(lines[field.Line-1])
+		entry := DocEntry{
+			Key:       field.Key,
+			Section:   sections[field.Line-1],
+			Default:   unquoteValue(value),
+			Generated: field.Generated,
+		}
+
+		comments := field.Comments
+		if headings[field.Line-1] {
+			comments = comments[1:]
+		}
+		entry.Description = strings.Join(comments, " ")
+
+		if field.Generated {
+			generator, err := g.describeGenerator(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", g.config.TemplatePath, field.Line, err)
+			}
+			entry.Generator = generator
+		} else {
+			entry.Default = field.Default
+			if looksSecret(field.Key, field.Default) {
+				entry.Default = docsSecretValue
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// describeGenerator explains how the placeholders of a template value are filled in
+func (g *Generator) describeGenerator(value string) (string, error) {
+	placeholders, err := placeholdersIn(value)
+	if err != nil {
+		return "", err
+	}
+
+	kind := "random"
+	if g.config.MasterKey != nil {
+		kind = "derived"
+	}
+
+	var descriptions []string
+	for _, p := range placeholders {
+		length, charset := g.placeholderSpec(p)
+		description := fmt.Sprintf("%s %s, %d chars", kind, charset, length)
+		if p.file != "" {
+			description += ", written to " + p.file
+		}
+		if len(placeholders) > 1 {
+			description = p.name + ": " + description
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, "; "), nil
+}
+
+// docSections returns the section of each key line and the key lines whose comment group
+// is the heading of their section
+func docSections(lines []string) (sections map[int]string, headings map[int]bool) {
+	sections = make(map[int]string)
+	headings = make(map[int]bool)
+	isBlank := func(i int) bool { return i < 0 || i >= len(lines) || strings.TrimSpace(lines[i]) == "" }
+	isKey := func(i int) bool { return !isBlank(i) && !isCommentOrEmpty(lines[i]) }
+
+	section := ""
+	for i, line := range lines {
+		if isBlank(i) {
+			continue
+		}
+
+		if isCommentOrEmpty(line) {
+			// A comment run standing alone between blank lines, followed by another comment
+			// run rather than a key, is a heading of its own
+			if !isBlank(i+1) || !isBlank(commentRunStart(lines, i)-1) {
+				continue
+			}
+			next := i + 1
+			for next < len(lines) && isBlank(next) {
+				next++
+			}
+			if next < len(lines) && !isKey(next) {
+				if title := firstCommentText(lines, commentRunStart(lines, i), i); title != "" {
+					section = title
+				}
+			}
+			continue
+		}
+
+		group := commentGroupIndices(lines, i)
+		if len(group) > 0 && isBlank(group[0]-1) {
+			detached := isBlank(i - 1)
+			if detached || isKey(i+1) {
+				if title := firstCommentText(lines, group[0], group[len(group)-1]); title != "" {
+					section = title
+					headings[i] = true
+				}
+			}
+		}
+		sections[i] = section
+	}
+	return sections, headings
+}
+
+// commentRunStart returns the first line of the run of comment lines ending at end
+func commentRunStart(lines []string, end int) int {
+	start := end
+	for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#") {
+		start--
+	}
+	return start
+}
+
+// firstCommentText returns the text of the first comment line between start and end
+// that is not an annotation
+func firstCommentText(lines []string, start, end int) string {
+	for i := start; i <= end; i++ {
+		if _, _, ok := parseAnnotation(lines[i]); ok {
+			continue
+		}
+		if text := commentText(lines[i]); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// RenderDocs renders doc entries as a table in the given format
+func RenderDocs(entries []DocEntry, format DocsFormat) ([]byte, error) {
+	switch format {
+	case DocsMarkdown:
+		return renderMarkdownDocs(entries), nil
+	case DocsHTML:
+		return renderHTMLDocs(entries), nil
+	default:
+		return nil, fmt.Errorf("unknown docs format %q; use markdown or html", format)
+	}
+}
+
+// docsColumns are the headers of the documentation table
+var docsColumns = []string{"Key", "Section", "Description", "Default", "Generated", "Generator"}
+
+// cells returns the plain text of each column of an entry
+func (e DocEntry) cells() []string {
+	generated := "no"
+	if e.Generated {
+		generated = "yes"
+	}
+	return []string{e.Key, e.Section, e.Description, e.Default, generated, e.Generator}
+}
+
+func renderMarkdownDocs(entries []DocEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("| " + strings.Join(docsColumns, " | ") + " |\n")
+	buf.WriteString("|" + strings.Repeat("---|", len(docsColumns)) + "\n")
+
+	for _, entry := range entries {
+		cells := entry.cells()
+		for i, cell := range cells {
+			cell = strings.ReplaceAll(cell, "|", `\|`)
+			// Keys and values are code; an empty default stays an empty cell
+			if (i == 0 || i == 3) && cell != "" && cell != docsSecretValue {
+				cell = markdownCode(cell)
+			}
+			cells[i] = cell
+		}
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return buf.Bytes()
+}
+
+// markdownCode wraps text in a code span with enough backticks for the ones it contains
+func markdownCode(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+func renderHTMLDocs(entries []DocEntry) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<table>\n<thead>\n<tr>")
+	for _, column := range docsColumns {
+		buf.WriteString("<th>" + column + "</th>")
+	}
+	buf.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	for _, entry := range entries {
+		buf.WriteString("<tr>")
+		for i, cell := range entry.cells() {
+			cell = html.EscapeString(cell)
+			if (i == 0 || i == 3) && cell != "" && cell != docsSecretValue {
+				cell = "<code>" + cell + "</code>"
+			}
+			buf.WriteString("<td>" + cell + "</td>")
+		}
+		buf.WriteString("</tr>\n")
+	}
+	buf.WriteString("</tbody>\n</table>\n")
+	return buf.Bytes()
+}
+
+// UpdateDocs replaces the region between DocsStartMarker and DocsEndMarker of an existing
+// document with content, keeping the markers and everything around them
+func UpdateDocs(path string, content []byte) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	document := string(data)
+
+	start := strings.Index(document, DocsStartMarker)
+	end := strings.Index(document, DocsEndMarker)
+	if start < 0 || end < 0 || end < start {
+		return fmt.Errorf("%s has no %s ... %s region to update", path, DocsStartMarker, DocsEndMarker)
+	}
+
+	eol := "\n"
+	if strings.Contains(document, "\r\n") {
+		eol = "\r\n"
+	}
+	region := strings.ReplaceAll(string(content), "\n", eol)
+
+	updated := document[:start+len(DocsStartMarker)] + eol + region + document[end:]
+	if updated == document {
+		return nil
+	}
+	if err := writeFileAtomic(path, []byte(updated), 0); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package generator
 
 import (
-	"bufio"
+	"bytes"
 	"crypto/rand"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"regexp"
 	"strings"
+	"time"
 )
 
 // CharsetType defines the type of character set to use for random values
@@ -31,10 +33,59 @@ const (
 // Config holds configuration for the env generator
 type Config struct {
 	TemplatePath string
-	OutputPath   string
-	Force        bool
-	ValueLength  int
-	Charset      CharsetType
+	// Overlays are templates layered on top of TemplatePath in order; later ones win
+	Overlays    []string
+	OutputPath  string
+	Force       bool
+	ValueLength int
+	Charset     CharsetType
+
+	// MasterKey switches value generation from random to HKDF derivation when set
+	MasterKey       []byte
+	DeriveNamespace string
+	DeriveVersion   string
+
+	// FileMode overrides the output file permissions; zero keeps the existing mode or uses DefaultFileMode
+	FileMode os.FileMode
+
+	// NoBackup disables the backup taken before --force overwrites an existing file
+	NoBackup bool
+	// BackupRetention is the number of backups kept; zero uses DefaultBackupRetention
+	BackupRetention int
+
+	// Format selects the output layout; empty means FormatDotenv
+	Format Format
+	// NestSeparator nests keys in structured formats, e.g. "__" turns DB__HOST into db.host
+	NestSeparator string
+	// YAMLComments keeps template comments as YAML comments
+	YAMLComments bool
+	// ManifestName and ManifestNamespace set the metadata of Kubernetes manifests
+	ManifestName      string
+	ManifestNamespace string
+
+	// EOL overrides the output line endings; empty or EOLAuto keeps the detected style
+	EOL EOLStyle
+
+	// MaxLineSize limits the length of a single line in bytes; zero means unlimited
+	MaxLineSize int
+
+	// LockTimeout bounds the wait for a concurrent run; zero uses DefaultLockTimeout
+	LockTimeout time.Duration
+
+	// Dialect selects the quoting of generated values in dotenv files; empty means DialectDotenv
+	Dialect Dialect
+
+	// Profile selects the profile-scoped template lines to use; empty uses only unscoped lines
+	Profile string
+
+	// Shared holds the values of ${shared:...} placeholders across templates; nil keeps them
+	// to the template
+	Shared *SharedValues
+
+	// Presets are the named generator options of ${name:preset=...} placeholders
+	Presets map[string]Preset
+	// KeyOverrides set the generator options of the placeholders of a key that have none
+	KeyOverrides map[string]Preset
 }
 
 // EnvLineType represents the type of line in an env file
@@ -53,6 +104,9 @@ type EnvLine struct {
 	Raw   string // The original line content
 	Key   string // Only set for LineTypeKeyValue (trimmed)
 	Value string // Only set for LineTypeKeyValue (original format)
+
+	// Secret marks values that hold generated secrets, for formats that store them separately
+	Secret bool
 }
 
 // TemplateInfo holds information about a key from the template
@@ -64,7 +118,25 @@ type TemplateInfo struct {
 
 // Generator is responsible for generating .env files
 type Generator struct {
-	config Config
+	config     Config
+	backupPath string
+	warnings   []string
+
+	// secretFiles maps the paths of file= placeholders to the values written there
+	secretFiles map[string]string
+
+	// schema holds the template annotations the written values are validated against
+	schema *Schema
+
+	// templateSources locates each line returned by readTemplateFile in its template file
+	templateSources []lineSource
+
+	// profiles holds every profile the template declares, whether selected or not
+	profiles map[string]bool
+	// sharedNames holds the placeholder names of @shared keys, which don't vary by profile
+	sharedNames map[string]bool
+	// outputValues holds the values of the existing output that template conditions read
+	outputValues map[string]string
 }
 
 // New creates a new Generator instance
@@ -78,6 +150,14 @@ func New(config Config) *Generator {
 		config.Charset = CharsetAlphanumeric
 	}
 
+	if config.DeriveNamespace == "" {
+		config.DeriveNamespace = DefaultDeriveNamespace
+	}
+
+	if config.DeriveVersion == "" {
+		config.DeriveVersion = DefaultDeriveVersion
+	}
+
 	return &Generator{
 		config: config,
 	}
@@ -92,25 +172,64 @@ func New(config Config) *Generator {
 // 4. Placeholders ${...} in new keys trigger value generation
 // 5. --force flag regenerates values for existing keys with placeholders in template
 func (g *Generator) Generate() error {
-	// STEP 1: Read template lines
-	templateLines, err := g.readTemplateFile()
+	if _, err := g.renderer(defaultLineFormat); err != nil {
+		return err
+	}
+	// Only dotenv output keeps the # genenv:derive comment the rotation checks rely on
+	if g.config.MasterKey != nil && g.config.Format != "" && g.config.Format != FormatDotenv {
+		return fmt.Errorf("values derived from a master key need the dotenv format; --format %s would drop the derivation context", g.config.Format)
+	}
+	dialect, err := g.dialect()
 	if err != nil {
 		return err
 	}
 
-	// STEP 2: Parse template to extract key information and line indices
-	templateInfo := g.parseTemplateInfo(templateLines)
+	// Hold the lock for the whole read-merge-write cycle so concurrent runs don't drop additions
+	unlock, err := g.lockOutputFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	// STEP 3: Check if .env file exists
-	existingLines, err := g.readEnvFileWithStructure(g.config.OutputPath)
+	// STEP 1: Check if .env file exists
+	// Any failure other than a missing file must not lead to the file being overwritten
+	existingLines, existingFormat, err := g.readOutputFile()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read existing output file: %w", err)
+	}
 	outputExists := (err == nil)
+	// Template conditions read the output as it is under the lock
+	g.setOutputValues(existingLines)
+
+	// STEP 2: Read template lines
+	templateLines, templateFormat, err := g.readTemplateFile()
+	if err != nil {
+		return err
+	}
+
+	// STEP 3: Parse template to extract key information and line indices
+	templateInfo := g.parseTemplateInfo(templateLines)
+	g.schema, err = g.parseTemplateSchema(templateLines)
+	if err != nil {
+		return err
+	}
+	if err := g.collectSharedNames(templateInfo); err != nil {
+		return err
+	}
+	if outputExists && g.config.Format != "" && g.config.Format != FormatDotenv {
+		canonicalizeKeys(existingLines, templateInfo)
+	}
 
 	// Shared placeholder values across all operations
 	placeholderValues := make(map[string]string)
+	g.recoverSharedValues(templateInfo, placeholderValues)
+	if err := g.offerSharedValues(templateInfo, existingLines); err != nil {
+		return err
+	}
 
 	if !outputExists {
 		// No existing .env file - create from template
-		return g.generateFromTemplate(templateLines, templateInfo, placeholderValues)
+		return g.generateFromTemplate(templateLines, templateFormat, templateInfo, placeholderValues, dialect)
 	}
 
 	// STEP 4: .env exists - preserve it and add missing keys
@@ -142,7 +261,11 @@ func (g *Generator) Generate() error {
 				if err != nil {
 					return err
 				}
-				outputLines = append(outputLines, replaceValueInLine(envLine.Raw, newValue))
+				line, err := replaceValueInLine(envLine.Raw, newValue, dialect)
+				if err != nil {
+					return err
+				}
+				outputLines = append(outputLines, line)
 			} else {
 				// Preserve as-is
 				outputLines = append(outputLines, envLine.Raw)
@@ -155,6 +278,17 @@ func (g *Generator) Generate() error {
 		}
 	}
 
+	// Recreate secret files that kept keys point to but that no longer exist
+	if err := g.syncSecretFiles(templateLines, existingKeys, templateInfo, placeholderValues); err != nil {
+		return err
+	}
+
+	// Keep the recorded derivation context in sync with the values
+	outputLines, err = g.applyDeriveHeader(outputLines)
+	if err != nil {
+		return err
+	}
+
 	// STEP 7: Add missing keys with their comment groups
 	if len(missingKeys) > 0 {
 		// Add a separator if the file doesn't end with an empty line
@@ -163,19 +297,35 @@ func (g *Generator) Generate() error {
 		}
 
 		for _, key := range missingKeys {
-			keyGroup := g.buildKeyGroupFromTemplate(templateLines, key, templateInfo, placeholderValues)
+			keyGroup, err := g.buildKeyGroupFromTemplate(templateLines, key, templateInfo, placeholderValues, dialect)
+			if err != nil {
+				return err
+			}
 			outputLines = append(outputLines, keyGroup...)
 		}
 	}
 
-	// STEP 8: Write output
-	return g.writeOutputFile(outputLines)
+	// STEP 8: Back up the previous file, and the secret files it points to, before --force
+	// replaces their values
+	if g.config.Force {
+		if err := g.backupOutputFile(g.changedSecretFiles()); err != nil {
+			return err
+		}
+	}
+
+	// STEP 9: Write output
+	return g.writeOutputFile(outputLines, existingFormat, secretKeys(templateInfo, existingLines))
 }
 
 // generateFromTemplate generates a new .env file from template (when .env doesn't exist)
-func (g *Generator) generateFromTemplate(templateLines []string, templateInfo map[string]TemplateInfo, placeholderValues map[string]string) error {
+func (g *Generator) generateFromTemplate(templateLines []string, format lineFormat, templateInfo map[string]TemplateInfo, placeholderValues map[string]string, dialect Dialect) error {
 	var outputLines []string
 
+	// Record the derivation context so a later version bump can be detected
+	if g.config.MasterKey != nil {
+		outputLines = append(outputLines, g.deriveHeader())
+	}
+
 	for _, line := range templateLines {
 		if isCommentOrEmpty(line) {
 			outputLines = append(outputLines, line)
@@ -195,14 +345,18 @@ func (g *Generator) generateFromTemplate(templateLines []string, templateInfo ma
 			if err != nil {
 				return err
 			}
-			outputLines = append(outputLines, replaceValueInLine(line, newValue))
+			line, err := replaceValueInLine(line, newValue, dialect)
+			if err != nil {
+				return err
+			}
+			outputLines = append(outputLines, line)
 		} else {
 			// Use line as-is
 			outputLines = append(outputLines, line)
 		}
 	}
 
-	return g.writeOutputFile(outputLines)
+	return g.writeOutputFile(outputLines, format, secretKeys(templateInfo, nil))
 }
 
 // findMissingKeys returns keys that are in template but not in existing .env
@@ -230,7 +384,7 @@ func (g *Generator) findMissingKeys(templateLines []string, existingKeys map[str
 }
 
 // buildKeyGroupFromTemplate builds a group of lines for a key including its comment group
-func (g *Generator) buildKeyGroupFromTemplate(templateLines []string, targetKey string, templateInfo map[string]TemplateInfo, placeholderValues map[string]string) []string {
+func (g *Generator) buildKeyGroupFromTemplate(templateLines []string, targetKey string, templateInfo map[string]TemplateInfo, placeholderValues map[string]string, dialect Dialect) ([]string, error) {
 	var result []string
 
 	// Find the line index for this key
@@ -248,7 +402,7 @@ func (g *Generator) buildKeyGroupFromTemplate(templateLines []string, targetKey
 	}
 
 	if keyLineIndex == -1 {
-		return result
+		return result, nil
 	}
 
 	// Extract comment group before this key
@@ -262,31 +416,41 @@ func (g *Generator) buildKeyGroupFromTemplate(templateLines []string, targetKey
 	if templateEntry.HasPlaceholder {
 		newValue, err := g.generateValueFromTemplate(templateEntry.Value, placeholderValues)
 		if err != nil {
-			// If generation fails, use the line as-is
-			result = append(result, keyLine)
-		} else {
-			result = append(result, replaceValueInLine(keyLine, newValue))
+			return nil, err
 		}
+		line, err := replaceValueInLine(keyLine, newValue, dialect)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, line)
 	} else {
 		result = append(result, keyLine)
 	}
 
-	return result
+	return result, nil
 }
 
 // extractCommentGroup extracts comment lines that belong to a key
 // It looks backward from the key line to find all related comments
 func (g *Generator) extractCommentGroup(lines []string, keyLineIndex int) []string {
 	var comments []string
+	for _, i := range commentGroupIndices(lines, keyLineIndex) {
+		comments = append(comments, lines[i])
+	}
+	return comments
+}
+
+// commentGroupIndices returns the indices of the comment lines that belong to a key, in order
+func commentGroupIndices(lines []string, keyLineIndex int) []int {
+	var indices []int
 
 	// Look backward from the key line
 	for i := keyLineIndex - 1; i >= 0; i-- {
-		line := lines[i]
-		trimmed := strings.TrimSpace(line)
+		trimmed := strings.TrimSpace(lines[i])
 
 		if trimmed == "" {
 			// Empty line - stop if we already have comments, otherwise skip
-			if len(comments) > 0 {
+			if len(indices) > 0 {
 				break
 			}
 			continue
@@ -294,20 +458,19 @@ func (g *Generator) extractCommentGroup(lines []string, keyLineIndex int) []stri
 
 		if strings.HasPrefix(trimmed, "#") {
 			// Comment line - add to the beginning
-			comments = append([]string{line}, comments...)
+			indices = append([]int{i}, indices...)
 		} else {
 			// Non-comment, non-empty line - stop looking
 			break
 		}
 	}
 
-	return comments
+	return indices
 }
 
 // parseTemplateInfo parses template lines and extracts key information
 func (g *Generator) parseTemplateInfo(lines []string) map[string]TemplateInfo {
 	templateInfo := make(map[string]TemplateInfo)
-	placeholderRe := regexp.MustCompile(`\${[^}]+}`)
 
 	for _, line := range lines {
 		if isCommentOrEmpty(line) {
@@ -336,94 +499,145 @@ func (g *Generator) generateValueFromTemplate(templateValue string, placeholderV
 	// Handle escaped placeholders
 	value := strings.ReplaceAll(templateValue, `\${`, escapeMarker)
 
-	placeholderRe := regexp.MustCompile(`\${([^}]+)}`)
-
 	// Replace all placeholders
+	var genErr error
 	result := placeholderRe.ReplaceAllStringFunc(value, func(match string) string {
-		placeholderName := placeholderRe.FindStringSubmatch(match)[1]
-
-		// Reuse existing value for same placeholder name
-		if existingValue, exists := placeholderValues[placeholderName]; exists {
-			return existingValue
+		p, err := parsePlaceholder(placeholderRe.FindStringSubmatch(match)[1])
+		if err != nil {
+			if genErr == nil {
+				genErr = err
+			}
+			return match
 		}
 
-		// Generate new value
-		newValue, err := g.generateSecureValue()
+		// Reuse existing value for same placeholder name, or generate a new one
+		newValue, err := g.resolvePlaceholder(p, placeholderValues)
 		if err != nil {
+			if genErr == nil {
+				genErr = err
+			}
 			return match // Keep placeholder on error
 		}
 
-		placeholderValues[placeholderName] = newValue
 		return newValue
 	})
 
+	if genErr != nil {
+		return "", genErr
+	}
+
 	// Restore escaped placeholders
 	result = strings.ReplaceAll(result, escapeMarker, `${`)
 
 	return result, nil
 }
 
-// readTemplateFile reads the template file
-func (g *Generator) readTemplateFile() ([]string, error) {
-	file, err := os.Open(g.config.TemplatePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open template file: %w", err)
+// placeholderSpec returns the length and character set of the values of a placeholder
+func (g *Generator) placeholderSpec(p placeholder) (int, CharsetType) {
+	length := g.config.ValueLength
+	if p.length > 0 {
+		length = p.length
 	}
-	defer file.Close()
+	charset := g.config.Charset
+	if p.charset != "" {
+		charset = p.charset
+	}
+	return length, charset
+}
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// readTemplate reads a single template file along with its line format
+func (g *Generator) readTemplate(path string) ([]string, lineFormat, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, lineFormat{}, fmt.Errorf("failed to open template file: %w", err)
 	}
+	defer file.Close()
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading template file: %w", err)
+	lines, format, err := readLinesWithFormat(file, g.config.MaxLineSize)
+	if err != nil {
+		return nil, lineFormat{}, fmt.Errorf("error reading template file: %w", err)
 	}
 
-	return lines, nil
+	return lines, format, nil
 }
 
 // readEnvFileWithStructure reads an env file and returns structured line information
-func (g *Generator) readEnvFileWithStructure(path string) ([]EnvLine, error) {
+func (g *Generator) readEnvFileWithStructure(path string) ([]EnvLine, lineFormat, error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, lineFormat{}, err
 	}
 	defer file.Close()
 
-	var lines []EnvLine
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		rawLine := scanner.Text()
-
-		if isCommentOrEmpty(rawLine) {
-			lines = append(lines, EnvLine{
-				Type: LineTypeComment,
-				Raw:  rawLine,
-			})
-		} else if key, value, ok := parseKeyValue(rawLine); ok {
-			lines = append(lines, EnvLine{
-				Type:  LineTypeKeyValue,
-				Raw:   rawLine,
-				Key:   key,
-				Value: value,
-			})
-		} else {
-			// Treat unparseable lines as comments
-			lines = append(lines, EnvLine{
-				Type: LineTypeComment,
-				Raw:  rawLine,
-			})
+	rawLines, format, err := readLinesWithFormat(file, g.config.MaxLineSize)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	return parseEnvLines(rawLines), format, nil
+}
+
+// readOutputFile reads the existing output file as env lines, decoding structured formats
+func (g *Generator) readOutputFile() ([]EnvLine, lineFormat, error) {
+	if g.config.Format == "" || g.config.Format == FormatDotenv {
+		return g.readEnvFileWithStructure(g.config.OutputPath)
+	}
+
+	data, err := os.ReadFile(g.config.OutputPath)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	_, format, err := readLinesWithFormat(bytes.NewReader(data), g.config.MaxLineSize)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	renderer, err := g.renderer(format)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	lines, err := renderer.Parse(data)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	return lines, format, nil
+}
+
+// parseEnvLines classifies raw lines of an env file
+func parseEnvLines(rawLines []string) []EnvLine {
+	lines := make([]EnvLine, 0, len(rawLines))
+	for _, rawLine := range rawLines {
+		lines = append(lines, parseEnvLine(rawLine))
+	}
+	return lines
+}
+
+// parseEnvLine classifies a single raw line of an env file
+func parseEnvLine(rawLine string) EnvLine {
+	if isCommentOrEmpty(rawLine) {
+		return EnvLine{
+			Type: LineTypeComment,
+			Raw:  rawLine,
 		}
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if key, value, ok := parseKeyValue(rawLine); ok {
+		return EnvLine{
+			Type:  LineTypeKeyValue,
+			Raw:   rawLine,
+			Key:   key,
+			Value: value,
+		}
 	}
 
-	return lines, nil
+	// Treat unparseable lines as comments
+	return EnvLine{
+		Type: LineTypeComment,
+		Raw:  rawLine,
+	}
 }
 
 // isCommentOrEmpty checks if a line is a comment or empty
@@ -444,47 +658,107 @@ func parseKeyValue(line string) (key, value string, ok bool) {
 }
 
 // replaceValueInLine replaces the value part of a key=value line while preserving the original format
-func replaceValueInLine(originalLine, newValue string) string {
+// The new value is written in template syntax; dialects other than dotenv re-quote it for their consumer.
+func replaceValueInLine(originalLine, newValue string, dialect Dialect) (string, error) {
 	// Find the first = sign
 	idx := strings.Index(originalLine, "=")
 	if idx == -1 {
-		return originalLine
+		return originalLine, nil
 	}
+
+	value, err := encodeValue(strings.TrimSpace(originalLine[:idx]), newValue, dialect)
+	if err != nil {
+		return "", err
+	}
+
 	// Return key part (including =) + new value
-	return originalLine[:idx+1] + newValue
+	return originalLine[:idx+1] + value, nil
 }
 
-// writeOutputFile writes processed lines to the output file
-func (g *Generator) writeOutputFile(lines []string) error {
-	file, err := os.Create(g.config.OutputPath)
+// writeOutputFile atomically writes processed lines to the output file
+// The format carries the line endings, BOM and final newline of the file being replaced
+// or, for new files, of the template, unless --eol overrides the line endings.
+// The configured renderer decides the layout; secrets tells it which keys hold generated values.
+func (g *Generator) writeOutputFile(lines []string, format lineFormat, secrets map[string]bool) error {
+	renderer, err := g.renderer(format.withEOL(g.config.EOL))
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return err
 	}
-	defer file.Close()
+	dialect, err := g.dialect()
+	if err != nil {
+		return err
+	}
+	g.checkDialect(lines, dialect)
 
-	writer := bufio.NewWriter(file)
+	envLines := make([]EnvLine, 0, len(lines))
 	for _, line := range lines {
-		if _, err := writer.WriteString(line + "\n"); err != nil {
-			return fmt.Errorf("failed to write to output file: %w", err)
-		}
+		envLine := parseEnvLine(line)
+		envLine.Secret = secrets[envLine.Key]
+		envLines = append(envLines, envLine)
+	}
+
+	data, err := renderer.Render(envLines)
+	if err != nil {
+		return fmt.Errorf("failed to render output file: %w", err)
 	}
 
-	if err := writer.Flush(); err != nil {
-		return fmt.Errorf("failed to flush output file: %w", err)
+	// Secret files go first so the env file never points at a value that wasn't written
+	if err := g.writeSecretFiles(); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(g.config.OutputPath, data, g.config.FileMode); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	// The file is kept so the offending values can be fixed in place
+	if g.schema != nil {
+		dotenv := g.config.Format == "" || g.config.Format == FormatDotenv
+		if violations := g.schema.Validate(g.config.OutputPath, envLines, dotenv); len(violations) > 0 {
+			return &ValidationError{Violations: violations}
+		}
 	}
 
 	return nil
 }
 
-// generateSecureValue generates a cryptographically secure random value
-func (g *Generator) generateSecureValue() (string, error) {
-	charset := getCharset(g.config.Charset)
-	length := g.config.ValueLength
+// secretKeys returns the keys whose values are generated secrets
+// Template keys are secret when their value has a placeholder; keys only found in the
+// existing output keep the classification they were read with.
+func secretKeys(templateInfo map[string]TemplateInfo, existingLines []EnvLine) map[string]bool {
+	secrets := make(map[string]bool)
+	for _, line := range existingLines {
+		if line.Type == LineTypeKeyValue && line.Secret {
+			secrets[line.Key] = true
+		}
+	}
+	for key, info := range templateInfo {
+		secrets[key] = info.HasPlaceholder
+	}
+	return secrets
+}
+
+// generateSecureValue generates a cryptographically secure value for a placeholder
+// Values are random unless a master key is configured, in which case they are derived
+func (g *Generator) generateSecureValue(placeholderName string) (string, error) {
+	return g.generateValue(placeholder{name: placeholderName})
+}
+
+// generateValue generates the value of a placeholder, honoring its length and charset options
+func (g *Generator) generateValue(p placeholder) (string, error) {
+	length, charsetType := g.placeholderSpec(p)
+	charset := getCharset(charsetType)
 
 	result := make([]byte, length)
 	randomBytes := make([]byte, length)
 
-	if _, err := rand.Read(randomBytes); err != nil {
+	if g.config.MasterKey != nil {
+		derived, err := g.deriveBytes(g.derivationName(p), generatorSpec(charsetType, length), length)
+		if err != nil {
+			return "", err
+		}
+		randomBytes = derived
+	} else if _, err := rand.Read(randomBytes); err != nil {
 		return "", err
 	}
 
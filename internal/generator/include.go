@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Directives that pull other templates into a template
+const (
+	// includeDirective inlines another template in place of the directive
+	includeDirective = "include"
+	// extendsDirective layers the template on top of another one, like an overlay
+	extendsDirective = "extends"
+)
+
+// readLayer reads a template file as a layer, resolving its directives
+// Conditions can refer to the keys outer looks up, as well as to the keys of the template.
+func (g *Generator) readLayer(path string, outer keyLookup) (templateLayer, lineFormat, error) {
+	lines, format, err := g.readTemplate(path)
+	if err != nil {
+		return templateLayer{}, lineFormat{}, err
+	}
+	layer, err := g.resolveDirectives(path, lines, []string{path}, outer)
+	if err != nil {
+		return templateLayer{}, lineFormat{}, err
+	}
+	return layer, format, nil
+}
+
+// resolveDirectives builds the layer of a template's lines
+// Lines scoped to other profiles than Config.Profile are left out, and lines of the selected
+// profile replace the definitions of their keys that apply to every profile. Lines of @if
+// blocks whose condition doesn't hold are left out too, and @repeat blocks are expanded in
+// place. An @include is replaced by the lines of the included template, set apart by blank
+// lines so comment groups don't run into each other. The template is then layered on top of
+// the templates it @extends, in order. Paths are relative to the template declaring them,
+// and chain holds the templates being resolved to detect cycles.
+func (g *Generator) resolveDirectives(path string, lines []string, chain []string, outer keyLookup) (templateLayer, error) {
+	var layer templateLayer
+	var parents []templateLayer
+	separate := false
+
+	// Conditions see the keys above them: those of this template, of the templates it
+	// extends so far, and of the template pulling it in
+	var conds conditionals
+	dropped := false
+	defined := func(key string) (string, bool) {
+		if value, ok := layerLookup(&layer)(key); ok {
+			return value, true
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			if value, ok := layerLookup(&parents[i])(key); ok {
+				return value, true
+			}
+		}
+		if outer != nil {
+			return outer(key)
+		}
+		return "", false
+	}
+
+	scope := profileScope{selected: g.config.Profile}
+	scopedKeys := make(map[string]bool)
+	defer func() {
+		if g.profiles == nil {
+			g.profiles = make(map[string]bool)
+		}
+		for profile := range scope.seen {
+			g.profiles[profile] = true
+		}
+	}()
+
+	// Lines of @repeat blocks are put back in the queue once for every instance
+	var queue templateLayer
+	for i, line := range lines {
+		queue.append(line, lineSource{path: path, line: i + 1})
+	}
+
+	for len(queue.lines) > 0 {
+		line, source := queue.lines[0], queue.sources[0]
+		queue.lines, queue.sources = queue.lines[1:], queue.sources[1:]
+
+		line, profiles, ok, err := scope.scope(line)
+		if err != nil {
+			return templateLayer{}, fmt.Errorf("%s: %w", source, err)
+		}
+		if !ok {
+			continue
+		}
+
+		name, arg, isAnnotation := parseAnnotation(line)
+		if isAnnotation {
+			handled, err := conds.handle(g, name, arg, source, defined)
+			if err != nil {
+				return templateLayer{}, fmt.Errorf("%s: %w", source, err)
+			}
+			if handled {
+				continue
+			}
+		}
+		if !conds.active() {
+			dropped = true
+			continue
+		}
+		if !scope.applies(profiles) {
+			continue
+		}
+
+		switch name {
+		case repeatDirective:
+			if !isAnnotation {
+				break
+			}
+			r, err := g.parseRepetition(arg, defined)
+			if err != nil {
+				return templateLayer{}, fmt.Errorf("%s: %w", source, err)
+			}
+			block, rest, err := takeRepeatBlock(queue, source)
+			if err != nil {
+				return templateLayer{}, err
+			}
+			expanded, err := r.expand(block)
+			if err != nil {
+				return templateLayer{}, err
+			}
+			queue = templateLayer{
+				lines:   append(expanded.lines, rest.lines...),
+				sources: append(expanded.sources, rest.sources...),
+			}
+			continue
+		case endRepeatDirective:
+			if isAnnotation {
+				return templateLayer{}, fmt.Errorf("%s: @%s without a matching @%s", source, endRepeatDirective, repeatDirective)
+			}
+		}
+
+		// Don't leave two blank lines where a block was dropped
+		blank := strings.TrimSpace(line) == ""
+		if dropped && blank && (len(layer.lines) == 0 || strings.TrimSpace(layer.lines[len(layer.lines)-1]) == "") {
+			continue
+		}
+		dropped = false
+
+		if !isAnnotation || (name != includeDirective && name != extendsDirective) {
+			if separate && !blank {
+				layer.append("", lineSource{path: path})
+			}
+			separate = false
+
+			// Lines of the selected profile take the place of the lines for every profile
+			if key, _, isKey := parseKeyValue(line); isKey && !isCommentOrEmpty(line) {
+				switch {
+				case profiles != nil && !scopedKeys[key] && lastKeyIndex(layer.lines, key) >= 0:
+					scopedKeys[key] = true
+					layer = overrideWithProfile(layer, line, source)
+					continue
+				case profiles != nil:
+					scopedKeys[key] = true
+				case scopedKeys[key]:
+					continue
+				}
+			}
+			layer.append(line, source)
+			continue
+		}
+
+		if arg == "" {
+			return templateLayer{}, fmt.Errorf("%s: @%s needs the path of a template", source, name)
+		}
+		target := arg
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		for _, ancestor := range chain {
+			if samePath(ancestor, target) {
+				return templateLayer{}, fmt.Errorf("%s: @%s %s creates a cycle: %s -> %s", source, name, arg, strings.Join(chain, " -> "), target)
+			}
+		}
+
+		targetLines, _, err := g.readTemplate(target)
+		if err != nil {
+			return templateLayer{}, fmt.Errorf("%s: @%s %s: %w", source, name, arg, err)
+		}
+		included, err := g.resolveDirectives(target, targetLines, append(chain[:len(chain):len(chain)], target), defined)
+		if err != nil {
+			return templateLayer{}, err
+		}
+
+		if name == extendsDirective {
+			parents = append(parents, included)
+			continue
+		}
+		if len(layer.lines) > 0 && strings.TrimSpace(layer.lines[len(layer.lines)-1]) != "" {
+			layer.append("", lineSource{path: path})
+		}
+		layer.lines = append(layer.lines, included.lines...)
+		layer.sources = append(layer.sources, included.sources...)
+		separate = len(included.lines) > 0 && strings.TrimSpace(included.lines[len(included.lines)-1]) != ""
+	}
+
+	if err := conds.close(); err != nil {
+		return templateLayer{}, err
+	}
+
+	if len(parents) == 0 {
+		return layer, nil
+	}
+	base := parents[0]
+	for _, parent := range parents[1:] {
+		base = mergeLayers(base, parent)
+	}
+	return mergeLayers(base, layer), nil
+}
+
+// samePath checks if two paths name the same file, comparing their absolute forms
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratorIncludeExtends tests resolving @include and @extends relative to the declaring template
+func TestGeneratorIncludeExtends(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := os.MkdirAll(filepath.Join(tempDir, "shared"), 0755); err != nil {
+		t.Fatalf("Failed to create shared dir: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(tempDir, "api"), 0755); err != nil {
+		t.Fatalf("Failed to create api dir: %v", err)
+	}
+	paths := writeTemplates(t, tempDir,
+		[2]string{"shared/common.example", "# Session signing key\n# @required\nSESSION_SECRET=${session_secret}\n"},
+		[2]string{"base.example", "# Base\nLOG_LEVEL=info\nDB_PASSWORD=${db_password}\n"},
+		[2]string{"api/.env.example", "# @extends ../base.example\nLOG_LEVEL=debug\n# @include ../shared/common.example\nAPI_DB_URL=postgres://api:${db_password}@db/api\n"},
+	)
+	templatePath, outputPath := paths[2], filepath.Join(tempDir, "api", ".env")
+
+	config := Config{TemplatePath: templatePath, OutputPath: outputPath}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	expected := "# Base\nLOG_LEVEL=debug\nDB_PASSWORD=<v>\n\n# Session signing key\n# @required\nSESSION_SECRET=<v>\n\nAPI_DB_URL=<v>\n"
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read .env file: %v", err)
+	}
+	content := string(data)
+	env := parseEnvFile(content)
+	for _, key := range []string{"DB_PASSWORD", "SESSION_SECRET", "API_DB_URL"} {
+		content = strings.Replace(content, key+"="+env[key], key+"=<v>", 1)
+	}
+	if content != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", content, expected)
+	}
+
+	// Comment groups of included keys travel with them when they are appended
+	os.WriteFile(outputPath, []byte("LOG_LEVEL=warn\n"), 0600)
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate .env file: %v", err)
+	}
+	if updated, _ := os.ReadFile(outputPath); !strings.Contains(string(updated), "\n# Session signing key\n# @required\nSESSION_SECRET=") {
+		t.Errorf("Included comment group was not appended with its key:\n%s", updated)
+	}
+
+	// Check points at the template the annotation was included from
+	os.WriteFile(outputPath, []byte("SESSION_SECRET=\n"), 0600)
+	violations, err := New(config).Check()
+	if err != nil {
+		t.Fatalf("Failed to check .env file: %v", err)
+	}
+	if len(violations) != 1 || violations[0].TemplateFile != paths[0] || violations[0].Annotation.Line != 2 {
+		t.Errorf("Expected a violation at %s:2, got %v", paths[0], violations)
+	}
+}
+
+// TestLintIncludedTemplate tests that lint diagnostics point at the included file and line
+func TestLintIncludedTemplate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"common.example", "APP_NAME=demo\nEMPTY=${}\n"},
+		[2]string{".env.example", "# @include common.example\nAPP_NAME=other\n"},
+	)
+
+	diagnostics, err := New(Config{TemplatePath: paths[1]}).Lint()
+	if err != nil {
+		t.Fatalf("Failed to lint template: %v", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.File != paths[0] || d.Line != 2 || d.Code != "empty-placeholder" {
+		t.Errorf("Unexpected diagnostic %s", d)
+	}
+	if d := diagnostics[1]; d.File != paths[1] || d.Line != 2 || d.Code != "duplicate-key" || !strings.Contains(d.Message, "at "+paths[0]+":1") {
+		t.Errorf("Unexpected diagnostic %s", d)
+	}
+}
+
+// TestIncludeCycle tests that templates including each other are rejected
+func TestIncludeCycle(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"a.example", "# @include b.example\nA=1\n"},
+		[2]string{"b.example", "# @extends a.example\nB=1\n"},
+	)
+
+	_, err = New(Config{TemplatePath: paths[0]}).Schema()
+	if err == nil || !strings.Contains(err.Error(), "creates a cycle") {
+		t.Fatalf("Expected a cycle error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), paths[1]+":1") {
+		t.Errorf("Cycle error should point at the directive, got %v", err)
+	}
+
+	missing := writeTemplates(t, tempDir, [2]string{"c.example", "# @include missing.example\n"})
+	if _, err := New(Config{TemplatePath: missing[0]}).Schema(); err == nil || !strings.Contains(err.Error(), missing[0]+":1") {
+		t.Errorf("Expected an error pointing at the directive, got %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonRenderer renders env lines as a JSON object, nested by separator when one is set
+type jsonRenderer struct {
+	separator string
+	eol       string
+}
+
+// Render writes the keys in order; JSON has no comments, so comment lines are dropped
+func (r jsonRenderer) Render(lines []EnvLine) ([]byte, error) {
+	root, err := buildValueTree(lines, r.separator)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSONObject(&buf, root, ""); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\n")
+
+	return withLineEndings(buf.Bytes(), r.eol), nil
+}
+
+// Parse reads a JSON object back into env lines, keeping the key order of the file
+func (r jsonRenderer) Parse(data []byte) ([]EnvLine, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	root := &valueNode{branch: true}
+	if err := readJSONObject(decoder, root); err != nil {
+		return nil, fmt.Errorf("invalid JSON output file: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON output file: unexpected data after object")
+	}
+
+	return flattenValueTree(root, r.separator), nil
+}
+
+// writeJSONObject writes a branch node as an indented JSON object
+func writeJSONObject(buf *bytes.Buffer, node *valueNode, indent string) error {
+	if len(node.children) == 0 {
+		buf.WriteString("{}")
+		return nil
+	}
+
+	buf.WriteString("{\n")
+	for i, child := range node.children {
+		buf.WriteString(indent + "  ")
+		buf.Write(marshalJSONString(child.key))
+		buf.WriteString(": ")
+		if child.branch {
+			if err := writeJSONObject(buf, child, indent+"  "); err != nil {
+				return err
+			}
+		} else {
+			buf.Write(marshalJSONString(child.value))
+		}
+		if i < len(node.children)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(indent + "}")
+
+	return nil
+}
+
+// marshalJSONString encodes s as a JSON string without HTML escaping
+func marshalJSONString(s string) []byte {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+// readJSONObject reads the next JSON object from decoder into node, preserving key order
+// Scalars other than strings are kept in their JSON spelling; null becomes an empty value.
+func readJSONObject(decoder *json.Decoder, node *valueNode) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("expected an object, got %v", token)
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+
+		child := &valueNode{key: key}
+		node.children = append(node.children, child)
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		trimmed := bytes.TrimSpace(raw)
+
+		switch {
+		case len(trimmed) > 0 && trimmed[0] == '{':
+			child.branch = true
+			if err := readJSONObject(json.NewDecoder(bytes.NewReader(trimmed)), child); err != nil {
+				return err
+			}
+		case len(trimmed) > 0 && trimmed[0] == '[':
+			return fmt.Errorf("arrays are not supported (key %s)", key)
+		case len(trimmed) > 0 && trimmed[0] == '"':
+			if err := json.Unmarshal(trimmed, &child.value); err != nil {
+				return err
+			}
+		case string(trimmed) == "null":
+			child.value = ""
+		default:
+			child.value = strings.TrimSpace(string(trimmed))
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
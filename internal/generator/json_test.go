@@ -0,0 +1,136 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratorJSONFormat tests rendering JSON and preserving values on re-runs
+func TestGeneratorJSONFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `# Database
+DB__HOST=localhost
+DB__PASSWORD="${db_password}"
+API_KEY=${api_key}`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, "env.json")
+	config := Config{
+		TemplatePath:  templatePath,
+		OutputPath:    outputPath,
+		Format:        FormatJSON,
+		NestSeparator: "__",
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate JSON file: %v", err)
+	}
+
+	first := readJSONFile(t, outputPath)
+	db := first["db"].(map[string]any)
+	if db["host"] != "localhost" {
+		t.Errorf("Expected db.host to be localhost, got %v", db["host"])
+	}
+	password := db["password"].(string)
+	if len(password) != DefaultValueLength || strings.Contains(password, `"`) {
+		t.Errorf("Placeholder was not resolved and unquoted: %q", password)
+	}
+
+	// Re-running keeps existing values and adds keys that appeared in the template
+	templateContent += "\nNEW_SECRET=${new_secret}"
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate JSON file: %v", err)
+	}
+
+	second := readJSONFile(t, outputPath)
+	if second["db"].(map[string]any)["password"] != password {
+		t.Error("Existing password was not preserved")
+	}
+	if second["api_key"] != first["api_key"] {
+		t.Error("Existing API key was not preserved")
+	}
+	if _, ok := second["new_secret"]; !ok {
+		t.Error("New key was not added")
+	}
+}
+
+// TestGeneratorJSONRejectsInvalidExistingFile tests that a broken output file is not overwritten
+func TestGeneratorJSONRejectsInvalidExistingFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("SECRET=${secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, "env.json")
+	if err := os.WriteFile(outputPath, []byte(`{"SECRET": "keep me"`), 0600); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	err = New(Config{TemplatePath: templatePath, OutputPath: outputPath, Format: FormatJSON}).Generate()
+	if err == nil {
+		t.Fatal("Generate should fail on an invalid existing JSON file")
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if string(content) != `{"SECRET": "keep me"` {
+		t.Errorf("Invalid existing file was overwritten: %q", content)
+	}
+}
+
+// TestJSONRendererParse tests reading flat JSON with non-string scalars
+func TestJSONRendererParse(t *testing.T) {
+	lines, err := jsonRenderer{}.Parse([]byte(`{"PORT": 5432, "DEBUG": true, "EMPTY": null, "NAME": "a b"}`))
+	if err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, line := range lines {
+		got[line.Key] = unquoteValue(line.Value)
+	}
+	want := map[string]string{"PORT": "5432", "DEBUG": "true", "EMPTY": "", "NAME": "a b"}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: expected %q, got %q", key, value, got[key])
+		}
+	}
+
+	if _, err := (jsonRenderer{}).Parse([]byte(`{"LIST": [1, 2]}`)); err == nil {
+		t.Error("Expected arrays to be rejected")
+	}
+}
+
+// readJSONFile reads and decodes a JSON object file
+func readJSONFile(t *testing.T, path string) map[string]any {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read JSON file: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(content, &result); err != nil {
+		t.Fatalf("Generated file is not valid JSON: %v\n%s", err, content)
+	}
+	return result
+}
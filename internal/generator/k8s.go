@@ -0,0 +1,187 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultManifestName is the metadata.name of generated manifests when none is given
+const DefaultManifestName = "env"
+
+var (
+	// manifestNameRe matches valid Kubernetes object names (DNS subdomains)
+	manifestNameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// manifestKeyRe matches valid Secret and ConfigMap data keys
+	manifestKeyRe = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+)
+
+// k8sRenderer renders env lines as Kubernetes manifests
+// In split mode generated secrets go into a Secret and literal values into a ConfigMap;
+// otherwise everything goes into a single Secret.
+type k8sRenderer struct {
+	name      string
+	namespace string
+	split     bool
+	comments  bool
+	eol       string
+}
+
+// newK8sRenderer validates the manifest metadata and returns a renderer
+func newK8sRenderer(config Config, split bool, eol string) (k8sRenderer, error) {
+	name := config.ManifestName
+	if name == "" {
+		name = DefaultManifestName
+	}
+	if !manifestNameRe.MatchString(name) || len(name) > 253 {
+		return k8sRenderer{}, fmt.Errorf("invalid manifest name %q: must be a lowercase DNS subdomain", name)
+	}
+	if config.ManifestNamespace != "" && !manifestNameRe.MatchString(config.ManifestNamespace) {
+		return k8sRenderer{}, fmt.Errorf("invalid namespace %q: must be a lowercase DNS label", config.ManifestNamespace)
+	}
+
+	return k8sRenderer{
+		name:      name,
+		namespace: config.ManifestNamespace,
+		split:     split,
+		comments:  config.YAMLComments,
+		eol:       eol,
+	}, nil
+}
+
+// Render writes a Secret with base64-encoded data, preceded by a ConfigMap in split mode
+func (r k8sRenderer) Render(lines []EnvLine) ([]byte, error) {
+	var secretLines, configLines []EnvLine
+	var pending []EnvLine
+
+	for _, line := range lines {
+		if line.Type != LineTypeKeyValue {
+			pending = append(pending, line)
+			continue
+		}
+		if !manifestKeyRe.MatchString(line.Key) {
+			return nil, fmt.Errorf("key %s is not a valid Secret or ConfigMap key", line.Key)
+		}
+
+		if r.split && !line.Secret {
+			configLines = append(configLines, pending...)
+			configLines = append(configLines, line)
+		} else {
+			encoded := line
+			encoded.Value = base64.StdEncoding.EncodeToString([]byte(unquoteValue(line.Value)))
+			secretLines = append(secretLines, pending...)
+			secretLines = append(secretLines, encoded)
+		}
+		pending = nil
+	}
+
+	var buf bytes.Buffer
+	if r.split {
+		if err := r.writeManifest(&buf, "ConfigMap", configLines); err != nil {
+			return nil, err
+		}
+		buf.WriteString("---\n")
+	}
+	if err := r.writeManifest(&buf, "Secret", secretLines); err != nil {
+		return nil, err
+	}
+
+	return withLineEndings(buf.Bytes(), r.eol), nil
+}
+
+// writeManifest writes a single v1 object of the given kind holding lines as its data
+func (r k8sRenderer) writeManifest(buf *bytes.Buffer, kind string, lines []EnvLine) error {
+	data, err := buildValueTree(lines, "")
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString("apiVersion: v1\n")
+	buf.WriteString("kind: " + kind + "\n")
+	buf.WriteString("metadata:\n")
+	buf.WriteString("  name: " + r.name + "\n")
+	if r.namespace != "" {
+		buf.WriteString("  namespace: " + r.namespace + "\n")
+	}
+	if kind == "Secret" {
+		buf.WriteString("type: Opaque\n")
+	}
+
+	if len(data.children) == 0 {
+		buf.WriteString("data: {}\n")
+		return nil
+	}
+	buf.WriteString("data:\n")
+	writeYAMLMapping(buf, data, "  ", r.comments)
+
+	return nil
+}
+
+// Parse reads Secret and ConfigMap manifests back into env lines
+// Secret values are decoded and marked as secrets so they stay in the Secret on re-runs.
+func (r k8sRenderer) Parse(data []byte) ([]EnvLine, error) {
+	var lines []EnvLine
+
+	for i, document := range splitYAMLDocuments(string(data)) {
+		root, err := parseYAMLMapping(document)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest in document %d: %w", i+1, err)
+		}
+		if len(root.children) == 0 {
+			continue
+		}
+
+		kind := ""
+		if node := root.child("kind"); node != nil {
+			kind = node.value
+		}
+		if kind != "Secret" && kind != "ConfigMap" {
+			return nil, fmt.Errorf("unsupported manifest kind %q in document %d", kind, i+1)
+		}
+
+		for _, field := range []string{"data", "stringData"} {
+			node := root.child(field)
+			if node == nil {
+				continue
+			}
+			for _, entry := range node.children {
+				value := entry.value
+				if kind == "Secret" && field == "data" {
+					decoded, err := base64.StdEncoding.DecodeString(value)
+					if err != nil {
+						return nil, fmt.Errorf("invalid base64 value for %s in Secret: %w", entry.key, err)
+					}
+					value = string(decoded)
+				}
+
+				for _, comment := range entry.comments {
+					lines = append(lines, EnvLine{Type: LineTypeComment, Raw: comment})
+				}
+				line := parseEnvLine(entry.key + "=" + quoteValue(value))
+				line.Secret = kind == "Secret"
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+// splitYAMLDocuments splits a multi-document YAML stream on "---" separator lines
+func splitYAMLDocuments(content string) []string {
+	var documents []string
+	var current []string
+
+	for _, line := range strings.Split(content, "\n") {
+		if strings.TrimSpace(strings.TrimRight(line, "\r")) == "---" {
+			documents = append(documents, strings.Join(current, "\n"))
+			current = nil
+			continue
+		}
+		current = append(current, line)
+	}
+
+	return append(documents, strings.Join(current, "\n"))
+}
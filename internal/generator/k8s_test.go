@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratorK8sSecretFormat tests rendering a Secret and preserving values on re-runs
+func TestGeneratorK8sSecretFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `DB_HOST=localhost
+DB_PASSWORD=${db_password}`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, "secret.yaml")
+	config := Config{
+		TemplatePath:      templatePath,
+		OutputPath:        outputPath,
+		Format:            FormatK8sSecret,
+		ManifestName:      "app-env",
+		ManifestNamespace: "dev",
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate manifest: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	wantHeader := "apiVersion: v1\nkind: Secret\nmetadata:\n  name: app-env\n  namespace: dev\ntype: Opaque\ndata:\n"
+	if !strings.HasPrefix(string(content), wantHeader) {
+		t.Errorf("Unexpected manifest header:\n%s", content)
+	}
+	encodedHost := base64.StdEncoding.EncodeToString([]byte("localhost"))
+	if !strings.Contains(string(content), `DB_HOST: "`+encodedHost+`"`) {
+		t.Errorf("DB_HOST was not base64-encoded:\n%s", content)
+	}
+
+	// Re-running decodes the manifest and keeps the generated password
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate manifest: %v", err)
+	}
+	again, _ := os.ReadFile(outputPath)
+	if string(again) != string(content) {
+		t.Errorf("Re-run changed the manifest:\n%s\nvs\n%s", content, again)
+	}
+}
+
+// TestGeneratorK8sSplitFormat tests splitting generated secrets and literals into two manifests
+func TestGeneratorK8sSplitFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `DB_HOST=localhost
+DB_PASSWORD=${db_password}`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, "manifests.yaml")
+	config := Config{TemplatePath: templatePath, OutputPath: outputPath, Format: FormatK8sSplit}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate manifests: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	documents := splitYAMLDocuments(string(content))
+	if len(documents) != 2 {
+		t.Fatalf("Expected a ConfigMap and a Secret, got:\n%s", content)
+	}
+	if !strings.Contains(documents[0], "kind: ConfigMap") || !strings.Contains(documents[0], `DB_HOST: "localhost"`) {
+		t.Errorf("Literal value should be in the ConfigMap:\n%s", documents[0])
+	}
+	if !strings.Contains(documents[1], "kind: Secret") || !strings.Contains(documents[1], "DB_PASSWORD: ") {
+		t.Errorf("Generated value should be in the Secret:\n%s", documents[1])
+	}
+
+	// A key added by hand to the Secret stays there even though the template doesn't know it
+	lines, err := k8sRenderer{split: true}.Parse(content)
+	if err != nil {
+		t.Fatalf("Failed to parse manifests: %v", err)
+	}
+	lines = append(lines, EnvLine{Type: LineTypeKeyValue, Raw: "EXTRA=1", Key: "EXTRA", Value: "1", Secret: true})
+	secrets := secretKeys(map[string]TemplateInfo{}, lines)
+	if !secrets["DB_PASSWORD"] || secrets["DB_HOST"] || !secrets["EXTRA"] {
+		t.Errorf("Unexpected secret classification: %v", secrets)
+	}
+}
+
+// TestK8sRendererValidation tests rejecting invalid names and keys
+func TestK8sRendererValidation(t *testing.T) {
+	if _, err := newK8sRenderer(Config{ManifestName: "App_Env"}, false, "\n"); err == nil {
+		t.Error("Expected an invalid manifest name to be rejected")
+	}
+
+	renderer, err := newK8sRenderer(Config{}, false, "\n")
+	if err != nil {
+		t.Fatalf("Failed to create renderer: %v", err)
+	}
+	if _, err := renderer.Render([]EnvLine{parseEnvLine("BAD KEY=1")}); err == nil {
+		t.Error("Expected an invalid data key to be rejected")
+	}
+	if _, err := renderer.Parse([]byte("apiVersion: v1\nkind: Pod\n")); err == nil {
+		t.Error("Expected an unsupported kind to be rejected")
+	}
+}
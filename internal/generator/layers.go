@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// lineSource locates a template line in the file it was read from
+type lineSource struct {
+	path string
+	line int // 1-based; zero for lines added while merging
+}
+
+// String formats the source as path:line
+func (s lineSource) String() string {
+	return fmt.Sprintf("%s:%d", s.path, s.line)
+}
+
+// templateLayer holds the lines of one or more merged templates and where each came from
+type templateLayer struct {
+	lines   []string
+	sources []lineSource
+}
+
+// append adds a line to the layer
+func (l *templateLayer) append(line string, source lineSource) {
+	l.lines = append(l.lines, line)
+	l.sources = append(l.sources, source)
+}
+
+// readTemplateFile reads the template and its overlays, merged into one set of lines,
+// along with the line format of the base template
+// The source of every merged line is recorded in g.templateSources.
+func (g *Generator) readTemplateFile() ([]string, lineFormat, error) {
+	merged, format, err := g.readLayer(g.config.TemplatePath, nil)
+	if err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	for _, path := range g.config.Overlays {
+		overlay, _, err := g.readLayer(path, layerLookup(&merged))
+		if err != nil {
+			return nil, lineFormat{}, err
+		}
+		merged = mergeLayers(merged, overlay)
+	}
+
+	if err := g.applyPresets(merged); err != nil {
+		return nil, lineFormat{}, err
+	}
+	if err := checkPlaceholderSpecs(merged); err != nil {
+		return nil, lineFormat{}, err
+	}
+
+	g.templateSources = merged.sources
+	return merged.lines, format, nil
+}
+
+// isTemplateKeyLine checks if a template line assigns a key
+func isTemplateKeyLine(lines []string, index int) bool {
+	if index < 0 || index >= len(lines) || isCommentOrEmpty(lines[index]) {
+		return false
+	}
+	_, _, ok := parseKeyValue(lines[index])
+	return ok
+}
+
+// ownCommentGroup returns the comment group of a key unless it heads a block of keys,
+// in which case it describes the whole block rather than the key alone
+func ownCommentGroup(lines []string, keyLineIndex int) []int {
+	if isTemplateKeyLine(lines, keyLineIndex+1) {
+		return nil
+	}
+	return commentGroupIndices(lines, keyLineIndex)
+}
+
+// lastKeyIndex returns the index of the last line defining key, or -1
+func lastKeyIndex(lines []string, key string) int {
+	for i := len(lines) - 1; i >= 0; i-- {
+		if k, _, ok := parseKeyValue(lines[i]); ok && !isCommentOrEmpty(lines[i]) && k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeLayers layers overlay on top of base
+// A key the overlay redefines keeps its place in base with the overlay's line, and its own
+// comment group, if any, replaces the base one. Everything else of the overlay is appended
+// after base so the sections of each template stay together.
+func mergeLayers(base, overlay templateLayer) templateLayer {
+	consumed := make(map[int]bool)
+
+	for i, line := range overlay.lines {
+		if !isTemplateKeyLine(overlay.lines, i) {
+			continue
+		}
+		key, _, _ := parseKeyValue(line)
+		baseIndex := lastKeyIndex(base.lines, key)
+		if baseIndex < 0 {
+			continue
+		}
+
+		var replacement templateLayer
+		start := baseIndex
+		if group := ownCommentGroup(overlay.lines, i); len(group) > 0 {
+			for _, index := range group {
+				replacement.append(overlay.lines[index], overlay.sources[index])
+				consumed[index] = true
+			}
+			if baseGroup := ownCommentGroup(base.lines, baseIndex); len(baseGroup) > 0 {
+				start = baseGroup[0]
+			}
+		}
+		replacement.append(line, overlay.sources[i])
+		consumed[i] = true
+
+		base = templateLayer{
+			lines:   append(append(append([]string{}, base.lines[:start]...), replacement.lines...), base.lines[baseIndex+1:]...),
+			sources: append(append(append([]lineSource{}, base.sources[:start]...), replacement.sources...), base.sources[baseIndex+1:]...),
+		}
+	}
+
+	var rest templateLayer
+	for i, line := range overlay.lines {
+		if consumed[i] {
+			continue
+		}
+		blank := strings.TrimSpace(line) == ""
+		// Drop blank lines left behind by moved keys
+		if blank && (len(rest.lines) == 0 || strings.TrimSpace(rest.lines[len(rest.lines)-1]) == "") {
+			continue
+		}
+		rest.append(line, overlay.sources[i])
+	}
+	for len(rest.lines) > 0 && strings.TrimSpace(rest.lines[len(rest.lines)-1]) == "" {
+		rest.lines, rest.sources = rest.lines[:len(rest.lines)-1], rest.sources[:len(rest.sources)-1]
+	}
+	if len(rest.lines) == 0 {
+		return base
+	}
+
+	merged := templateLayer{
+		lines:   append([]string{}, base.lines...),
+		sources: append([]lineSource{}, base.sources...),
+	}
+	if len(merged.lines) > 0 && strings.TrimSpace(merged.lines[len(merged.lines)-1]) != "" {
+		merged.append("", lineSource{path: rest.sources[0].path})
+	}
+	merged.lines = append(merged.lines, rest.lines...)
+	merged.sources = append(merged.sources, rest.sources...)
+	return merged
+}
+
+// checkPlaceholderSpecs reports placeholders that share a name, and therefore a value,
+// but ask for different lengths or character sets
+// Placeholders without options take the value of the name as it is and never conflict.
+func checkPlaceholderSpecs(layer templateLayer) error {
+	type spec struct {
+		p      placeholder
+		source lineSource
+	}
+	specs := make(map[string]spec)
+
+	for i, line := range layer.lines {
+		if !isTemplateKeyLine(layer.lines, i) {
+			continue
+		}
+		_, value, _ := parseKeyValue(line)
+		placeholders, err := placeholdersIn(value)
+		if err != nil {
+			// Malformed placeholders are reported when the value is generated
+			continue
+		}
+
+		for _, p := range placeholders {
+			if p.length == 0 && p.charset == "" {
+				continue
+			}
+			previous, ok := specs[p.name]
+			if !ok {
+				specs[p.name] = spec{p: p, source: layer.sources[i]}
+				continue
+			}
+			if previous.p.length != p.length || previous.p.charset != p.charset {
+				return fmt.Errorf("placeholder ${%s} is generated with %s at %s but with %s at %s; placeholders sharing a name share one value",
+					p.name, describeSpec(previous.p), previous.source, describeSpec(p), layer.sources[i])
+			}
+		}
+	}
+	return nil
+}
+
+// describeSpec lists the generator options of a placeholder
+func describeSpec(p placeholder) string {
+	var options []string
+	if p.length > 0 {
+		options = append(options, "length="+strconv.Itoa(p.length))
+	}
+	if p.charset != "" {
+		options = append(options, "charset="+string(p.charset))
+	}
+	return strings.Join(options, ",")
+}
@@ -0,0 +1,143 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTemplates writes template files into dir and returns their paths in order
+func writeTemplates(t *testing.T, dir string, templates ...[2]string) []string {
+	t.Helper()
+	var paths []string
+	for _, template := range templates {
+		path := filepath.Join(dir, template[0])
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create template dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(template[1]), 0644); err != nil {
+			t.Fatalf("Failed to write template file: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// TestMergeLayers tests overriding keys in place and appending overlay sections
+func TestMergeLayers(t *testing.T) {
+	base := templateLayer{lines: strings.Split(`# Database
+DB_HOST=localhost
+DB_PASSWORD=${db_password}
+
+# Log level
+LOG_LEVEL=info`, "\n")}
+	overlay := templateLayer{lines: strings.Split(`# Log level of the API
+# @enum debug|info
+LOG_LEVEL=debug
+
+# API
+API_PORT=3000
+API_DB_URL=postgres://api:${db_password}@db/api`, "\n")}
+	for i := range base.lines {
+		base.sources = append(base.sources, lineSource{path: "base", line: i + 1})
+	}
+	for i := range overlay.lines {
+		overlay.sources = append(overlay.sources, lineSource{path: "api", line: i + 1})
+	}
+
+	merged := mergeLayers(base, overlay)
+	expected := `# Database
+DB_HOST=localhost
+DB_PASSWORD=${db_password}
+
+# Log level of the API
+# @enum debug|info
+LOG_LEVEL=debug
+
+# API
+API_PORT=3000
+API_DB_URL=postgres://api:${db_password}@db/api`
+	if got := strings.Join(merged.lines, "\n"); got != expected {
+		t.Errorf("Unexpected merge:\n%s", got)
+	}
+	if len(merged.sources) != len(merged.lines) {
+		t.Fatalf("Expected a source for every line, got %d for %d lines", len(merged.sources), len(merged.lines))
+	}
+	if merged.sources[6] != (lineSource{path: "api", line: 3}) {
+		t.Errorf("Overridden key should point at the overlay, got %s", merged.sources[6])
+	}
+	if merged.sources[1] != (lineSource{path: "base", line: 2}) {
+		t.Errorf("Base key should point at the base, got %s", merged.sources[1])
+	}
+}
+
+// TestGeneratorOverlays tests generating one output from a base template and an overlay
+func TestGeneratorOverlays(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"base.example", "# Shared\nDB_PASSWORD=${db_password:length=32}\nLOG_LEVEL=info\n"},
+		[2]string{"api.example", "LOG_LEVEL=debug\n\n# API\n# @type port\nAPI_PORT=3000\nAPI_DB_URL=postgres://api:${db_password}@db/api\n"},
+	)
+	outputPath := filepath.Join(tempDir, ".env")
+
+	config := Config{TemplatePath: paths[0], Overlays: paths[1:], OutputPath: outputPath}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	env := readEnv(t, outputPath)
+	if env["LOG_LEVEL"] != "debug" {
+		t.Errorf("Overlay should override LOG_LEVEL, got %q", env["LOG_LEVEL"])
+	}
+	if len(env["DB_PASSWORD"]) != 32 {
+		t.Errorf("Expected a 32 character password, got %q", env["DB_PASSWORD"])
+	}
+	if env["API_DB_URL"] != "postgres://api:"+env["DB_PASSWORD"]+"@db/api" {
+		t.Errorf("Layers should share placeholder values, got %q", env["API_DB_URL"])
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if !strings.HasPrefix(string(content), "# Shared\nDB_PASSWORD=") || !strings.Contains(string(content), "\n\n# API\n") {
+		t.Errorf("Sections should stay grouped by template:\n%s", content)
+	}
+
+	// Schema violations point at the template the annotation came from
+	os.WriteFile(outputPath, []byte("API_PORT=http\n"), 0600)
+	violations, err := New(config).Check()
+	if err != nil {
+		t.Fatalf("Failed to check .env file: %v", err)
+	}
+	if len(violations) != 1 || violations[0].TemplateFile != paths[1] || violations[0].Annotation.Line != 4 {
+		t.Errorf("Expected a violation at %s:4, got %v", paths[1], violations)
+	}
+}
+
+// TestGeneratorOverlayConflict tests reporting placeholders with conflicting options across layers
+func TestGeneratorOverlayConflict(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"base.example", "DB_PASSWORD=${db_password:length=32}\n"},
+		[2]string{"api.example", "API_DB_PASSWORD=${db_password:length=16}\n"},
+	)
+
+	err = New(Config{TemplatePath: paths[0], Overlays: paths[1:], OutputPath: filepath.Join(tempDir, ".env")}).Generate()
+	if err == nil {
+		t.Fatal("Expected an error for conflicting placeholder options")
+	}
+	for _, expected := range []string{"length=32 at " + paths[0] + ":1", "length=16 at " + paths[1] + ":1"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q in %q", expected, err)
+		}
+	}
+}
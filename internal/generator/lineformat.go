@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EOLStyle selects the line endings of the output file
+type EOLStyle string
+
+const (
+	// EOLAuto reproduces the line endings of the existing file, or of the template for new files
+	EOLAuto EOLStyle = "auto"
+	// EOLLF writes Unix line endings
+	EOLLF EOLStyle = "lf"
+	// EOLCRLF writes Windows line endings
+	EOLCRLF EOLStyle = "crlf"
+)
+
+// utf8BOM is the byte order mark some Windows editors put at the start of UTF-8 files
+const utf8BOM = "\uFEFF"
+
+// lineFormat describes the byte-level layout of a text file around its lines
+type lineFormat struct {
+	eol          string // "\n" or "\r\n"
+	bom          bool   // File starts with a UTF-8 BOM
+	finalNewline bool   // Last line is terminated
+}
+
+// defaultLineFormat is used for empty files and files without any line ending
+var defaultLineFormat = lineFormat{eol: "\n", finalNewline: true}
+
+// ErrLineTooLong is returned when a line exceeds the configured maximum line size
+var ErrLineTooLong = errors.New("line too long")
+
+// readLinesWithFormat reads lines from r and detects their line ending style, BOM and final newline
+// Lines may be of any length unless maxLineSize is positive, in which case longer lines fail
+// with ErrLineTooLong before more than maxLineSize bytes of them are buffered. This is not a
+// streaming reader: every line is returned at once because generation merges whole files, so
+// memory grows with the size of the file.
+// Mixed line endings resolve to the majority style, with ties going to the first line's style.
+func readLinesWithFormat(r io.Reader, maxLineSize int) ([]string, lineFormat, error) {
+	format := defaultLineFormat
+	var lines []string
+	var lf, crlf int
+	firstEOL := ""
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := readLine(reader, maxLineSize)
+		if errors.Is(err, ErrLineTooLong) {
+			return nil, lineFormat{}, fmt.Errorf("line %d: %w (limit is %d bytes)", len(lines)+1, err, maxLineSize)
+		}
+		if err != nil && err != io.EOF {
+			return nil, lineFormat{}, err
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+
+		if len(lines) == 0 && strings.HasPrefix(line, utf8BOM) {
+			format.bom = true
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+
+		var eol string
+		switch {
+		case strings.HasSuffix(line, "\r\n"):
+			eol = "\r\n"
+			crlf++
+		case strings.HasSuffix(line, "\n"):
+			eol = "\n"
+			lf++
+		}
+		if firstEOL == "" {
+			firstEOL = eol
+		}
+
+		format.finalNewline = eol != ""
+		lines = append(lines, strings.TrimSuffix(line, eol))
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	switch {
+	case crlf > lf, crlf == lf && firstEOL == "\r\n":
+		format.eol = "\r\n"
+	}
+
+	return lines, format, nil
+}
+
+// readLine reads a single line including its terminator
+// Unlike bufio.Scanner there is no fixed token limit; the line grows in buffer-sized chunks.
+func readLine(reader *bufio.Reader, maxLineSize int) (string, error) {
+	var line []byte
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if maxLineSize > 0 && len(bytes.TrimRight(line, "\r\n")) > maxLineSize {
+			return "", ErrLineTooLong
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return string(line), err
+	}
+}
+
+// withEOL returns the format with its line endings overridden by the given style
+func (f lineFormat) withEOL(style EOLStyle) lineFormat {
+	switch style {
+	case EOLLF:
+		f.eol = "\n"
+	case EOLCRLF:
+		f.eol = "\r\n"
+	}
+	return f
+}
+
+// join renders lines back into file content using the format
+func (f lineFormat) join(lines []string) []byte {
+	var buf bytes.Buffer
+	if f.bom {
+		buf.WriteString(utf8BOM)
+	}
+	for i, line := range lines {
+		buf.WriteString(line)
+		if i < len(lines)-1 || f.finalNewline {
+			buf.WriteString(f.eol)
+		}
+	}
+	return buf.Bytes()
+}
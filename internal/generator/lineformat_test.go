@@ -0,0 +1,222 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratorPreservesCRLF tests that merging into a CRLF file doesn't mix line endings
+func TestGeneratorPreservesCRLF(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("EXISTING=1\nNEW_SECRET=${new_secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(outputPath, []byte("# Windows file\r\nEXISTING=mine\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if strings.Count(string(content), "\n") != strings.Count(string(content), "\r\n") {
+		t.Errorf("Output has mixed line endings: %q", content)
+	}
+	if !strings.HasPrefix(string(content), "# Windows file\r\nEXISTING=mine\r\n\r\nNEW_SECRET=") {
+		t.Errorf("Existing content was not preserved: %q", content)
+	}
+	if !strings.HasSuffix(string(content), "\r\n") {
+		t.Errorf("Final CRLF was not kept: %q", content)
+	}
+}
+
+// TestGeneratorPreservesBOMAndMissingFinalNewline tests that the BOM is kept out of keys and reproduced
+func TestGeneratorPreservesBOMAndMissingFinalNewline(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("FIRST=${first}\nSECOND=2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(outputPath, []byte(utf8BOM+"FIRST=mine"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	want := utf8BOM + "FIRST=mine\n\nSECOND=2"
+	if string(content) != want {
+		t.Errorf("Expected %q, got %q", want, content)
+	}
+}
+
+// TestGeneratorUsesTemplateFormatForNewFiles tests that new files follow the template's layout
+func TestGeneratorUsesTemplateFormatForNewFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte(utf8BOM+"A=1\r\nB=2\r\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	if string(content) != utf8BOM+"A=1\r\nB=2\r\n" {
+		t.Errorf("Template format was not reproduced: %q", content)
+	}
+
+	// --eol overrides the detected line endings
+	outputPath = filepath.Join(tempDir, ".env.lf")
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath, EOL: EOLLF}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	content, _ = os.ReadFile(outputPath)
+	if string(content) != utf8BOM+"A=1\nB=2\n" {
+		t.Errorf("EOL override was not applied: %q", content)
+	}
+}
+
+// TestReadLinesWithFormat tests line ending detection
+func TestReadLinesWithFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		lines   []string
+		format  lineFormat
+	}{
+		{"empty", "", nil, lineFormat{eol: "\n", finalNewline: true}},
+		{"lf", "A=1\nB=2\n", []string{"A=1", "B=2"}, lineFormat{eol: "\n", finalNewline: true}},
+		{"crlf", "A=1\r\nB=2\r\n", []string{"A=1", "B=2"}, lineFormat{eol: "\r\n", finalNewline: true}},
+		{"no final newline", "A=1\nB=2", []string{"A=1", "B=2"}, lineFormat{eol: "\n"}},
+		{"mixed majority", "A=1\nB=2\r\nC=3\r\n", []string{"A=1", "B=2", "C=3"}, lineFormat{eol: "\r\n", finalNewline: true}},
+		{"bom", utf8BOM + "A=1\n", []string{"A=1"}, lineFormat{eol: "\n", bom: true, finalNewline: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines, format, err := readLinesWithFormat(strings.NewReader(tt.content), 0)
+			if err != nil {
+				t.Fatalf("Failed to read lines: %v", err)
+			}
+			if strings.Join(lines, "|") != strings.Join(tt.lines, "|") {
+				t.Errorf("Expected lines %q, got %q", tt.lines, lines)
+			}
+			if format != tt.format {
+				t.Errorf("Expected format %+v, got %+v", tt.format, format)
+			}
+			// Mixed endings are normalized, everything else must round trip exactly
+			if tt.name != "mixed majority" && string(format.join(lines)) != tt.content {
+				t.Errorf("Round trip changed content: %q", format.join(lines))
+			}
+		})
+	}
+}
+
+// TestGeneratorHandlesMultiMegabyteLines tests that long inline values don't hit a token limit
+func TestGeneratorHandlesMultiMegabyteLines(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// A base64 service-account JSON can easily exceed bufio.Scanner's 64 KiB limit
+	longTemplateValue := strings.Repeat("QUJD", 1<<20)
+	longEnvValue := strings.Repeat("eHl6", 3<<19)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := "SERVICE_ACCOUNT=" + longTemplateValue + "\nCERT=${cert}\nSECRET=${secret}\n"
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	existingContent := "CERT=" + longEnvValue + "\n"
+	if err := os.WriteFile(outputPath, []byte(existingContent), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	envVars := parseEnvFile(string(content))
+
+	if envVars["CERT"] != longEnvValue {
+		t.Errorf("Existing long value was not preserved (got %d bytes)", len(envVars["CERT"]))
+	}
+	if envVars["SERVICE_ACCOUNT"] != longTemplateValue {
+		t.Errorf("Long template value was not copied (got %d bytes)", len(envVars["SERVICE_ACCOUNT"]))
+	}
+	if len(envVars["SECRET"]) != DefaultValueLength {
+		t.Errorf("SECRET was not generated: %q", envVars["SECRET"])
+	}
+}
+
+// TestGeneratorMaxLineSize tests that a configured line limit rejects longer lines
+func TestGeneratorMaxLineSize(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := "SHORT=1\nLONG=" + strings.Repeat("x", 1<<20) + "\n"
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	config := Config{
+		TemplatePath: templatePath,
+		OutputPath:   filepath.Join(tempDir, ".env"),
+		MaxLineSize:  64 << 10,
+	}
+	err = New(config).Generate()
+	if !errors.Is(err, ErrLineTooLong) {
+		t.Fatalf("Expected ErrLineTooLong, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Error should name the offending line: %v", err)
+	}
+
+	// The limit counts content only, so a line exactly at the limit passes
+	lines, _, err := readLinesWithFormat(strings.NewReader("ABC=123\r\n"), len("ABC=123"))
+	if err != nil || len(lines) != 1 {
+		t.Errorf("Line at the limit should be accepted, got %q, %v", lines, err)
+	}
+}
@@ -0,0 +1,245 @@
+package generator
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Severity tells how serious a lint diagnostic is
+type Severity string
+
+const (
+	// SeverityError marks template mistakes that produce wrong output
+	SeverityError Severity = "error"
+	// SeverityWarning marks suspicious lines that may be intended
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a single problem found in a template
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+}
+
+// String formats the diagnostic as file:line:col: severity: message [code]
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", d.File, d.Line, d.Column, d.Severity, d.Message, d.Code)
+}
+
+// Lint checks the template for mistakes that would otherwise only show up as odd output
+// Diagnostics are returned in line order and point at the file each line was included from;
+// the error is only set when the template or one it includes can't be read. Without a
+// profile, the lines of every profile the template declares are checked as well.
+func (g *Generator) Lint() ([]Diagnostic, error) {
+	diagnostics, err := g.lintProfile(g.config.Profile)
+	if err != nil || g.config.Profile != "" {
+		return diagnostics, err
+	}
+
+	profiles := make([]string, 0, len(g.profiles))
+	for profile := range g.profiles {
+		profiles = append(profiles, profile)
+	}
+	slices.Sort(profiles)
+
+	seen := make(map[Diagnostic]bool)
+	fileOrder := make(map[string]int)
+	for _, d := range diagnostics {
+		seen[d] = true
+	}
+	for _, source := range g.templateSources {
+		if _, ok := fileOrder[source.path]; !ok {
+			fileOrder[source.path] = len(fileOrder)
+		}
+	}
+
+	for _, profile := range profiles {
+		more, err := g.lintProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range more {
+			if !seen[d] {
+				seen[d] = true
+				diagnostics = append(diagnostics, d)
+			}
+		}
+	}
+
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		if a.File != b.File {
+			return fileOrder[a.File] - fileOrder[b.File]
+		}
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Column - b.Column
+	})
+	return diagnostics, nil
+}
+
+// lintProfile lints the template lines that apply to a profile
+func (g *Generator) lintProfile(profile string) ([]Diagnostic, error) {
+	scoped := *g
+	scoped.config.Profile = profile
+	lines, _, err := scoped.readTemplateFile()
+	if err != nil {
+		return nil, err
+	}
+	if g.profiles == nil {
+		g.profiles = scoped.profiles
+	}
+	if profile == g.config.Profile {
+		g.templateSources = scoped.templateSources
+	}
+
+	l := linter{sources: scoped.templateSources, keyLines: make(map[string]int)}
+	for i, line := range lines {
+		l.lintLine(i+1, line)
+	}
+	return l.diagnostics, nil
+}
+
+// linter collects diagnostics while walking the template line by line
+type linter struct {
+	sources     []lineSource   // Source of each template line, as merged by readTemplateFile
+	keyLines    map[string]int // Line each key was last defined on
+	diagnostics []Diagnostic
+}
+
+// report records a diagnostic at a byte offset of a line
+func (l *linter) report(lineNumber int, line string, offset int, severity Severity, code, format string, args ...any) {
+	source := l.sources[lineNumber-1]
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		File:     source.path,
+		Line:     source.line,
+		Column:   utf8.RuneCountInString(line[:offset]) + 1,
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintLine checks a single template line
+func (l *linter) lintLine(lineNumber int, line string) {
+	if isCommentOrEmpty(line) {
+		l.lintAnnotation(lineNumber, line)
+		return
+	}
+
+	key, value, ok := parseKeyValue(line)
+	keyOffset := len(line) - len(strings.TrimLeft(line, " \t"))
+	if !ok {
+		l.report(lineNumber, line, keyOffset, SeverityError, "invalid-line", "line is neither a comment nor a KEY=value assignment")
+		return
+	}
+	valueOffset := strings.Index(line, "=") + 1
+
+	if !shellKeyRe.MatchString(key) {
+		l.report(lineNumber, line, keyOffset, SeverityError, "invalid-key",
+			"%q is not a valid key; use letters, digits and _, not starting with a digit", key)
+	}
+	if previous, ok := l.keyLines[key]; ok {
+		location := fmt.Sprintf("on line %d", l.sources[previous-1].line)
+		if l.sources[previous-1].path != l.sources[lineNumber-1].path {
+			location = "at " + l.sources[previous-1].String()
+		}
+		l.report(lineNumber, line, keyOffset, SeverityWarning, "duplicate-key",
+			"%s is already defined %s; only this definition is used", key, location)
+	}
+	l.keyLines[key] = lineNumber
+
+	l.lintQuotes(lineNumber, line, value, valueOffset)
+	hasPlaceholder := l.lintPlaceholders(lineNumber, line, value, valueOffset)
+
+	if !hasPlaceholder && secretKeyRe.MatchString(key) && unquoteValue(value) != "" {
+		l.report(lineNumber, line, valueOffset, SeverityWarning, "literal-secret",
+			"%s looks like a secret but has a literal value; use a placeholder such as ${%s}", key, strings.ToLower(key))
+	}
+}
+
+// lintAnnotation reports schema annotations with invalid arguments
+func (l *linter) lintAnnotation(lineNumber int, line string) {
+	name, arg, ok := parseAnnotation(line)
+	if !ok {
+		return
+	}
+	field := Field{annotations: make(map[string]Annotation)}
+	if err := field.annotate(Annotation{Name: name, Arg: arg, Line: lineNumber}); err != nil {
+		l.report(lineNumber, line, strings.Index(line, annotationPrefix+name), SeverityError, "invalid-annotation", "%v", err)
+	}
+}
+
+// lintQuotes reports quoted values that are never closed
+func (l *linter) lintQuotes(lineNumber int, line, value string, valueOffset int) {
+	trimmed := strings.TrimLeft(value, " \t")
+	quoteOffset := valueOffset + len(value) - len(trimmed)
+	if trimmed == "" {
+		return
+	}
+
+	switch trimmed[0] {
+	case '"':
+		if closingQuote(trimmed) < 0 {
+			l.report(lineNumber, line, quoteOffset, SeverityError, "unbalanced-quote", "double quote is never closed")
+		}
+	case '\'':
+		if strings.IndexByte(trimmed[1:], '\'') < 0 {
+			l.report(lineNumber, line, quoteOffset, SeverityError, "unbalanced-quote", "single quote is never closed")
+		}
+	}
+}
+
+// lintPlaceholders reports empty, malformed and single-quoted placeholders
+// It returns whether the value has any placeholder genenv fills in.
+func (l *linter) lintPlaceholders(lineNumber int, line, value string, valueOffset int) bool {
+	found := false
+
+	for i := 0; i < len(value); i++ {
+		if !strings.HasPrefix(value[i:], "${") || (i > 0 && value[i-1] == '\\') {
+			continue
+		}
+		offset := valueOffset + i
+
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			l.report(lineNumber, line, offset, SeverityError, "unclosed-placeholder", "placeholder is missing its closing }")
+			return found
+		}
+		contents := value[i+2 : i+end]
+
+		if contents == "" {
+			l.report(lineNumber, line, offset, SeverityError, "empty-placeholder", "placeholder ${} has no name and is left in the output as-is")
+			continue
+		}
+		if _, err := parsePlaceholder(contents); err != nil {
+			l.report(lineNumber, line, offset, SeverityError, "invalid-placeholder", "%v", err)
+			continue
+		}
+		found = true
+
+		if insideSingleQuotes(value, i) {
+			l.report(lineNumber, line, offset, SeverityWarning, "single-quoted-placeholder",
+				"${%s} is inside single quotes, which dotenv loaders do not expand", contents)
+		}
+	}
+
+	return found
+}
+
+// insideSingleQuotes checks if the byte at index of a raw value is within a single-quoted value
+func insideSingleQuotes(value string, index int) bool {
+	trimmed := strings.TrimLeft(value, " \t")
+	start := len(value) - len(trimmed)
+	if !strings.HasPrefix(trimmed, "'") || index <= start {
+		return false
+	}
+	end := strings.IndexByte(trimmed[1:], '\'')
+	return end < 0 || index < start+1+end
+}
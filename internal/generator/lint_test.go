@@ -0,0 +1,67 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLint tests the diagnostics reported for common template mistakes
+func TestLint(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `# Clean lines produce no diagnostics
+DB_HOST=localhost
+DB_PASSWORD=${db_password}
+ESCAPED=\${literal}
+EMPTY=${}
+DB_HOST=db.internal
+1BAD-KEY=value
+QUOTED="unterminated
+SINGLE='${single}'
+PIN=${pin:size=4}
+API_TOKEN=abc123
+not an assignment
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	diagnostics, err := New(Config{TemplatePath: templatePath}).Lint()
+	if err != nil {
+		t.Fatalf("Failed to lint template: %v", err)
+	}
+
+	expected := []struct {
+		line     int
+		column   int
+		severity Severity
+		code     string
+	}{
+		{5, 7, SeverityError, "empty-placeholder"},
+		{6, 1, SeverityWarning, "duplicate-key"},
+		{7, 1, SeverityError, "invalid-key"},
+		{8, 8, SeverityError, "unbalanced-quote"},
+		{9, 9, SeverityWarning, "single-quoted-placeholder"},
+		{10, 5, SeverityError, "invalid-placeholder"},
+		{11, 11, SeverityWarning, "literal-secret"},
+		{12, 1, SeverityError, "invalid-line"},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("Expected %d diagnostics, got %d:\n%v", len(expected), len(diagnostics), diagnostics)
+	}
+	for i, want := range expected {
+		got := diagnostics[i]
+		if got.Line != want.line || got.Column != want.column || got.Severity != want.severity || got.Code != want.code {
+			t.Errorf("Diagnostic %d: got %s, want %d:%d %s [%s]", i, got, want.line, want.column, want.severity, want.code)
+		}
+		if got.File != templatePath {
+			t.Errorf("Diagnostic %d has file %s, want %s", i, got.File, templatePath)
+		}
+	}
+}
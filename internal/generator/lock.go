@@ -0,0 +1,54 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultLockTimeout is how long to wait for another run to release the output file
+	DefaultLockTimeout = 10 * time.Second
+
+	// lockSuffix is appended to the output path to name its lock file
+	lockSuffix = ".lock"
+	// lockPollInterval is how often a held lock is retried
+	lockPollInterval = 50 * time.Millisecond
+)
+
+// ErrLockTimeout is returned when the output file stays locked by another run
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// lockOutputFile takes an exclusive advisory lock guarding the output file
+// The lock file lives next to the symlink-resolved output so every alias shares it.
+func (g *Generator) lockOutputFile() (unlock func(), err error) {
+	target, err := resolveSymlink(g.config.OutputPath)
+	if err != nil {
+		return nil, err
+	}
+	return acquireLock(target+lockSuffix, g.config.LockTimeout, g.config.OutputPath)
+}
+
+// acquireLock takes an exclusive advisory lock on lockPath, which guards the file at path
+// A zero timeout waits DefaultLockTimeout; a negative one does not wait at all.
+func acquireLock(lockPath string, timeout time.Duration, path string) (unlock func(), err error) {
+	if timeout == 0 {
+		timeout = DefaultLockTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	for {
+		unlock, acquired, err := tryLock(lockPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock %s: %w", lockPath, err)
+		}
+		if acquired {
+			return unlock, nil
+		}
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("%w on %s after %s; another genenv run may be writing %s (raise --lock-timeout to wait longer)",
+				ErrLockTimeout, lockPath, max(timeout, 0), path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package generator
+
+import "os"
+
+// tryLock attempts to create the lock file exclusively
+// Without flock the lock file itself marks ownership, so it is removed on unlock.
+func tryLock(path string) (unlock func(), acquired bool, err error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
+	if os.IsExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	unlock = func() {
+		file.Close()
+		os.Remove(path)
+	}
+
+	return unlock, true, nil
+}
@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestGeneratorConcurrentRunsKeepAllKeys tests that parallel runs on one output don't lose additions
+func TestGeneratorConcurrentRunsKeepAllKeys(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	outputPath := filepath.Join(tempDir, ".env")
+	const runs = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		templatePath := filepath.Join(tempDir, fmt.Sprintf("service%d.example", i))
+		content := fmt.Sprintf("SERVICE_%d_SECRET=${secret_%d}\n", i, i)
+		if err := os.WriteFile(templatePath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write template file: %v", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Concurrent run failed: %v", err)
+		}
+	}
+
+	content, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	envVars := parseEnvFile(string(content))
+	for i := 0; i < runs; i++ {
+		if _, ok := envVars[fmt.Sprintf("SERVICE_%d_SECRET", i)]; !ok {
+			t.Errorf("SERVICE_%d_SECRET was lost by a concurrent run", i)
+		}
+	}
+}
+
+// TestGeneratorLockTimeout tests that a held lock makes Generate fail with ErrLockTimeout
+func TestGeneratorLockTimeout(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte("SECRET=${secret}\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	outputPath := filepath.Join(tempDir, ".env")
+
+	holder := New(Config{OutputPath: outputPath})
+	unlock, err := holder.lockOutputFile()
+	if err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+
+	gen := New(Config{TemplatePath: templatePath, OutputPath: outputPath, LockTimeout: 100 * time.Millisecond})
+	err = gen.Generate()
+	if !errors.Is(err, ErrLockTimeout) {
+		t.Fatalf("Expected ErrLockTimeout, got %v", err)
+	}
+	if _, statErr := os.Stat(outputPath); statErr == nil {
+		t.Error("Output file should not be written while locked")
+	}
+
+	// Once released, the next run succeeds
+	unlock()
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate after lock release: %v", err)
+	}
+}
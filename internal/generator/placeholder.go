@@ -0,0 +1,221 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// placeholderRe matches a ${...} placeholder and captures its contents
+var placeholderRe = regexp.MustCompile(`\${([^}]+)}`)
+
+// SecretFileMode is the permission of files written for the file= placeholder option
+const SecretFileMode os.FileMode = 0600
+
+// placeholder is a parsed ${name:option=value,...} placeholder
+type placeholder struct {
+	name    string
+	shared  bool        // In the shared namespace, e.g. ${shared:jwt_secret}
+	file    string      // Write the value to this file and put the path in the env file instead
+	length  int         // Overrides Config.ValueLength when positive
+	charset CharsetType // Overrides Config.Charset when set
+	preset  string      // Names a Config.Presets entry supplying the options not given
+}
+
+// parsePlaceholder parses the contents of a placeholder
+// Options follow the name after a colon as comma-separated key=value pairs. Names in the
+// shared namespace keep their shared: prefix.
+func parsePlaceholder(contents string) (placeholder, error) {
+	var p placeholder
+	rest := contents
+	if name, ok := strings.CutPrefix(strings.TrimSpace(contents), sharedNamespace+":"); ok {
+		p.shared, rest = true, name
+	}
+	name, options, hasOptions := strings.Cut(rest, ":")
+	p.name = strings.TrimSpace(name)
+	if p.name == "" {
+		return placeholder{}, fmt.Errorf("placeholder ${%s} has no name", contents)
+	}
+	if p.shared {
+		p.name = sharedNamespace + ":" + p.name
+	}
+	if !hasOptions {
+		return p, nil
+	}
+
+	for _, option := range strings.Split(options, ",") {
+		key, value, ok := strings.Cut(option, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !ok || value == "" {
+			return placeholder{}, fmt.Errorf("placeholder ${%s}: option %q must be written as key=value", contents, option)
+		}
+
+		switch key {
+		case "file":
+			p.file = value
+		case "length":
+			length, err := strconv.Atoi(value)
+			if err != nil || length <= 0 {
+				return placeholder{}, fmt.Errorf("placeholder ${%s}: length must be a positive number", contents)
+			}
+			p.length = length
+		case "charset":
+			if !validCharset(CharsetType(value)) {
+				return placeholder{}, fmt.Errorf("placeholder ${%s}: unknown charset %q", contents, value)
+			}
+			p.charset = CharsetType(value)
+		case "preset":
+			p.preset = value
+		default:
+			return placeholder{}, fmt.Errorf("placeholder ${%s}: unknown option %q", contents, key)
+		}
+	}
+
+	return p, nil
+}
+
+// validCharset checks if charset is one of the character sets values are generated from
+func validCharset(charset CharsetType) bool {
+	switch charset {
+	case CharsetAlphanumeric, CharsetAlphabetic, CharsetUppercase, CharsetLowercase, CharsetNumeric:
+		return true
+	}
+	return false
+}
+
+// placeholdersIn returns the unescaped placeholders of a template value
+func placeholdersIn(templateValue string) ([]placeholder, error) {
+	var placeholders []placeholder
+	for _, match := range placeholderRe.FindAllStringSubmatchIndex(templateValue, -1) {
+		if match[0] > 0 && templateValue[match[0]-1] == '\\' {
+			continue
+		}
+		p, err := parsePlaceholder(templateValue[match[2]:match[3]])
+		if err != nil {
+			return nil, err
+		}
+		placeholders = append(placeholders, p)
+	}
+	return placeholders, nil
+}
+
+// resolvePlaceholder returns the text that replaces a placeholder in the env file
+// Placeholders sharing a name share a value. With the file option the value goes to
+// the secret file, an existing secret file is kept unless --force is given, and the
+// env file gets the path.
+func (g *Generator) resolvePlaceholder(p placeholder, placeholderValues map[string]string) (string, error) {
+	value, exists := placeholderValues[p.name]
+
+	if !exists && p.file != "" && !g.config.Force {
+		content, err := os.ReadFile(g.secretFilePath(p.file))
+		switch {
+		case err == nil:
+			value, exists = strings.TrimSuffix(string(content), "\n"), true
+		case !errors.Is(err, fs.ErrNotExist):
+			return "", fmt.Errorf("failed to read secret file %s: %w", p.file, err)
+		}
+	}
+
+	if !exists && p.shared && g.config.Shared != nil {
+		length, charset := g.placeholderSpec(p)
+		sharedValue, err := g.config.Shared.value(p.name, length, charset, func() (string, error) { return g.generateValue(p) })
+		if err != nil {
+			return "", err
+		}
+		value, exists = sharedValue, true
+	}
+
+	if !exists {
+		newValue, err := g.generateValue(p)
+		if err != nil {
+			return "", err
+		}
+		value = newValue
+	}
+	placeholderValues[p.name] = value
+
+	if p.file == "" {
+		return value, nil
+	}
+
+	if g.secretFiles == nil {
+		g.secretFiles = make(map[string]string)
+	}
+	g.secretFiles[g.secretFilePath(p.file)] = value
+	return p.file, nil
+}
+
+// secretFilePath resolves a file= path, which is relative to the directory of the output file
+func (g *Generator) secretFilePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(g.config.OutputPath), path)
+}
+
+// syncSecretFiles resolves the file placeholders of keys that are kept from the existing file
+// so a secret file that was deleted is recreated for the path the env file still points to.
+func (g *Generator) syncSecretFiles(templateLines []string, existingKeys map[string]bool, templateInfo map[string]TemplateInfo, placeholderValues map[string]string) error {
+	for _, line := range templateLines {
+		key, _, ok := parseKeyValue(line)
+		if !ok || isCommentOrEmpty(line) || !existingKeys[key] {
+			continue
+		}
+
+		placeholders, err := placeholdersIn(templateInfo[key].Value)
+		if err != nil {
+			return err
+		}
+		for _, p := range placeholders {
+			if p.file == "" {
+				continue
+			}
+			path := g.secretFilePath(p.file)
+			if _, done := g.secretFiles[path]; done {
+				continue
+			}
+			_, known := placeholderValues[p.name]
+			if _, err := g.resolvePlaceholder(p, placeholderValues); err != nil {
+				return err
+			}
+			if _, err := os.Stat(path); !known && errors.Is(err, fs.ErrNotExist) {
+				g.warnf("secret file %s of %s was missing and has been regenerated; keys using ${%s} still hold the old value", p.file, key, p.name)
+			}
+		}
+	}
+	return nil
+}
+
+// changedSecretFiles returns the secret files whose content is not up to date, in order
+func (g *Generator) changedSecretFiles() []string {
+	var paths []string
+	for path, value := range g.secretFiles {
+		if content, err := os.ReadFile(path); err == nil && strings.TrimSuffix(string(content), "\n") == value {
+			continue
+		}
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// writeSecretFiles writes the values of file placeholders with SecretFileMode
+// Files whose content is already up to date are left untouched.
+func (g *Generator) writeSecretFiles() error {
+	for _, path := range g.changedSecretFiles() {
+		value := g.secretFiles[path]
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return fmt.Errorf("failed to create directory for secret file: %w", err)
+		}
+		if err := writeFileAtomic(path, []byte(value), SecretFileMode); err != nil {
+			return fmt.Errorf("failed to write secret file: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestParsePlaceholder tests parsing placeholder names and options
+func TestParsePlaceholder(t *testing.T) {
+	p, err := parsePlaceholder("db_password:file=./secrets/db_password")
+	if err != nil {
+		t.Fatalf("Failed to parse placeholder: %v", err)
+	}
+	if p.name != "db_password" || p.file != "./secrets/db_password" {
+		t.Errorf("Unexpected placeholder: %+v", p)
+	}
+
+	for _, contents := range []string{"", ":file=x", "name:file", "name:colour=red"} {
+		if _, err := parsePlaceholder(contents); err == nil {
+			t.Errorf("Expected an error for ${%s}", contents)
+		}
+	}
+}
+
+// TestGeneratorSecretFiles tests writing file= placeholders to secret files across runs
+func TestGeneratorSecretFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `DB_PASSWORD_FILE=${db_password:file=./secrets/db_password}
+DB_URL=postgres://app:${db_password}@db/app
+`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	secretPath := filepath.Join(tempDir, "secrets", "db_password")
+	config := Config{
+		TemplatePath: templatePath,
+		OutputPath:   outputPath,
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+
+	env := readEnv(t, outputPath)
+	if env["DB_PASSWORD_FILE"] != "./secrets/db_password" {
+		t.Errorf("Expected the secret file path in .env, got %q", env["DB_PASSWORD_FILE"])
+	}
+	secret := readSecretFile(t, secretPath)
+	if len(secret) != DefaultValueLength {
+		t.Errorf("Secret file has unexpected content: %q", secret)
+	}
+	if env["DB_URL"] != "postgres://app:"+secret+"@db/app" {
+		t.Errorf("Placeholders sharing a name should share the secret, got %q", env["DB_URL"])
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(secretPath)
+		if err != nil {
+			t.Fatalf("Failed to stat secret file: %v", err)
+		}
+		if info.Mode().Perm() != SecretFileMode {
+			t.Errorf("Expected secret file mode %o, got %o", SecretFileMode, info.Mode().Perm())
+		}
+	}
+
+	// A deleted secret file is recreated for the path .env still points to
+	if err := os.Remove(secretPath); err != nil {
+		t.Fatalf("Failed to remove secret file: %v", err)
+	}
+	gen := New(config)
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to re-generate .env file: %v", err)
+	}
+	recreated := readSecretFile(t, secretPath)
+	if recreated == "" {
+		t.Fatal("Secret file was not recreated")
+	}
+	if len(gen.Warnings()) != 1 {
+		t.Errorf("Expected a warning about the recreated secret file, got %q", gen.Warnings())
+	}
+
+	// An existing secret file is preserved and reused for new keys
+	if err := os.WriteFile(templatePath, []byte(templateContent+"DB_URL_RO=postgres://ro:${db_password}@db/app\n"), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate .env file: %v", err)
+	}
+	if readSecretFile(t, secretPath) != recreated {
+		t.Error("Existing secret file was overwritten")
+	}
+	if readEnv(t, outputPath)["DB_URL_RO"] != "postgres://ro:"+recreated+"@db/app" {
+		t.Error("New key does not use the value of the existing secret file")
+	}
+
+	// --force regenerates the secret file and the keys using it together
+	config.Force = true
+	config.NoBackup = true
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to force re-generate .env file: %v", err)
+	}
+	rotated := readSecretFile(t, secretPath)
+	if rotated == recreated {
+		t.Error("--force did not regenerate the secret file")
+	}
+	if readEnv(t, outputPath)["DB_URL"] != "postgres://app:"+rotated+"@db/app" {
+		t.Error("--force left .env inconsistent with the secret file")
+	}
+}
+
+// readEnv reads an env file into a map
+func readEnv(t *testing.T, path string) map[string]string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return parseEnvFile(string(content))
+}
+
+// readSecretFile reads the content of a secret file
+func readSecretFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read secret file: %v", err)
+	}
+	return string(content)
+}
+
+// TestGeneratorInvalidPlaceholderUpdate tests that a malformed placeholder of a key added to
+// an existing file fails the run instead of being written as-is
+func TestGeneratorInvalidPlaceholderUpdate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir, [2]string{".env.example", "A=${a}\n"})
+	outputPath := filepath.Join(tempDir, ".env")
+	config := Config{TemplatePath: paths[0], OutputPath: outputPath}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	before, _ := os.ReadFile(outputPath)
+
+	writeTemplates(t, tempDir, [2]string{".env.example", "A=${a}\nB=${b:lenght=8}\n"})
+	if err := New(config).Generate(); err == nil || !strings.Contains(err.Error(), `unknown option "lenght"`) {
+		t.Errorf("Expected an error for the unknown option, got %v", err)
+	}
+	if after, _ := os.ReadFile(outputPath); string(after) != string(before) {
+		t.Errorf("Expected the output to be left alone, got:\n%s", after)
+	}
+}
@@ -0,0 +1,293 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// profileAnnotation scopes a template line to profiles, e.g. LOG_LEVEL=debug  # @profile dev
+const profileAnnotation = "profile"
+
+// sharedAnnotation marks a key whose placeholders get the same value in every profile
+const sharedAnnotation = "shared"
+
+var (
+	// profileInlineRe matches a trailing @profile annotation and captures its profile list
+	profileInlineRe = regexp.MustCompile(`\s+#\s*` + annotationPrefix + profileAnnotation + `\s+(\S+)\s*$`)
+	// profileSectionRe matches a [profile name] section header and captures its profile list
+	// Other bracketed lines are left alone, so templates without profiles read as before.
+	profileSectionRe = regexp.MustCompile(`^\s*\[\s*` + profileAnnotation + `\s+([^\]]*)\]\s*$`)
+	// profileNameRe matches valid profile names
+	profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// allProfiles is the profile list of the section header that ends profile-scoped sections,
+// [profile *]
+const allProfiles = "*"
+
+// IsValidProfile checks if a profile name can be used in templates and file names
+func IsValidProfile(name string) bool {
+	return profileNameRe.MatchString(name)
+}
+
+// parseProfileList parses a comma or | separated list of profile names
+func parseProfileList(list string) ([]string, error) {
+	var profiles []string
+	for _, name := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == '|' }) {
+		name = strings.TrimSpace(name)
+		if !IsValidProfile(name) {
+			return nil, fmt.Errorf("invalid profile name %q; use letters, digits, _ and -", name)
+		}
+		profiles = append(profiles, name)
+	}
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("profile list %q is empty", list)
+	}
+	return profiles, nil
+}
+
+// profileScope tracks the profiles the lines of a template apply to while it is read
+type profileScope struct {
+	selected string   // Profile being generated; empty for the default profile
+	section  []string // Profiles of the current [profile name] section; nil outside sections
+	seen     map[string]bool
+}
+
+// scope returns the line without its @profile annotation and the profiles it applies to,
+// or nil for lines that apply to every profile. Section headers return ok=false.
+func (s *profileScope) scope(line string) (stripped string, profiles []string, ok bool, err error) {
+	if match := profileSectionRe.FindStringSubmatch(line); match != nil {
+		list := strings.TrimSpace(match[1])
+		if list == allProfiles {
+			s.section = nil
+			return "", nil, false, nil
+		}
+		s.section, err = parseProfileList(list)
+		if err != nil {
+			return "", nil, false, err
+		}
+		s.record(s.section)
+		return "", nil, false, nil
+	}
+
+	if !isCommentOrEmpty(line) {
+		if match := profileInlineRe.FindStringSubmatchIndex(line); match != nil {
+			profiles, err := parseProfileList(line[match[2]:match[3]])
+			if err != nil {
+				return "", nil, false, err
+			}
+			s.record(profiles)
+			return line[:match[0]], profiles, true, nil
+		}
+	}
+	return line, s.section, true, nil
+}
+
+// record remembers profiles declared by the template
+func (s *profileScope) record(profiles []string) {
+	if s.seen == nil {
+		s.seen = make(map[string]bool)
+	}
+	for _, profile := range profiles {
+		s.seen[profile] = true
+	}
+}
+
+// applies checks if a line scoped to profiles is part of the selected profile
+func (s *profileScope) applies(profiles []string) bool {
+	if profiles == nil {
+		return true
+	}
+	for _, profile := range profiles {
+		if profile == s.selected {
+			return true
+		}
+	}
+	return false
+}
+
+// overrideWithProfile puts a profile-scoped key line in place of the definition of the same
+// key that applies to every profile, like an overlay does, together with its own comment group
+// The lines of that comment group are at the end of layer when the key line is reached.
+func overrideWithProfile(layer templateLayer, line string, source lineSource) templateLayer {
+	candidate := templateLayer{
+		lines:   append(append([]string{}, layer.lines...), line),
+		sources: append(append([]lineSource{}, layer.sources...), source),
+	}
+	group := ownCommentGroup(candidate.lines, len(candidate.lines)-1)
+
+	start := len(layer.lines)
+	if len(group) > 0 {
+		start = group[0]
+	}
+	overlay := templateLayer{lines: candidate.lines[start:], sources: candidate.sources[start:]}
+	base := templateLayer{lines: layer.lines[:start], sources: layer.sources[:start]}
+	return mergeLayers(base, overlay)
+}
+
+// Profiles returns the profiles declared by the template and the templates it pulls in
+func (g *Generator) Profiles() ([]string, error) {
+	if _, _, err := g.readTemplateFile(); err != nil {
+		return nil, err
+	}
+	profiles := make([]string, 0, len(g.profiles))
+	for profile := range g.profiles {
+		profiles = append(profiles, profile)
+	}
+	slices.Sort(profiles)
+	return profiles, nil
+}
+
+// derivationName returns the name a placeholder value is derived from
+// Values differ per profile unless the placeholder is shared.
+func (g *Generator) derivationName(p placeholder) string {
+	if g.config.Profile == "" || g.sharedNames[p.name] || p.shared {
+		return p.name
+	}
+	return g.config.Profile + "/" + p.name
+}
+
+// collectSharedNames records the placeholder names used by keys annotated with @shared
+func (g *Generator) collectSharedNames(templateInfo map[string]TemplateInfo) error {
+	g.sharedNames = make(map[string]bool)
+	for _, field := range g.schema.Fields {
+		if !field.Shared {
+			continue
+		}
+		placeholders, err := placeholdersIn(templateInfo[field.Key].Value)
+		if err != nil {
+			return err
+		}
+		for _, p := range placeholders {
+			g.sharedNames[p.name] = true
+		}
+	}
+	return nil
+}
+
+// recoverSharedValues fills in the values of shared placeholders from the outputs of other
+// profiles; --force regenerates them instead
+func (g *Generator) recoverSharedValues(templateInfo map[string]TemplateInfo, placeholderValues map[string]string) {
+	if g.config.Profile == "" || g.config.Force || g.config.MasterKey != nil {
+		return
+	}
+	for name := range g.sharedNames {
+		if value, ok := g.sharedValue(name, templateInfo); ok {
+			placeholderValues[name] = value
+		}
+	}
+}
+
+// profileOutputPath returns the output path of another profile, following the naming of
+// the current output, e.g. .env.staging for dev when generating .env.dev
+// It returns false when the output doesn't follow that naming.
+func (g *Generator) profileOutputPath(profile string) (string, bool) {
+	dir, name := filepath.Split(g.config.OutputPath)
+	segment := "." + g.config.Profile
+	index := strings.Index(name+".", segment+".")
+	if g.config.Profile == "" || index < 0 {
+		return "", false
+	}
+
+	replacement := ""
+	if profile != "" {
+		replacement = "." + profile
+	}
+	return filepath.Join(dir, name[:index]+replacement+name[index+len(segment):]), true
+}
+
+// sharedValue looks for the value of a shared placeholder in the outputs of other profiles
+// so the profiles agree on it. Values are recovered by matching each output value against
+// its template value. Keys with a file= placeholder hold the path of the secret file rather
+// than the value, so they are skipped; the secret file itself is read when it is generated.
+func (g *Generator) sharedValue(name string, templateInfo map[string]TemplateInfo) (string, bool) {
+	others := []string{""}
+	for profile := range g.profiles {
+		others = append(others, profile)
+	}
+	slices.Sort(others)
+
+	for _, profile := range others {
+		if profile == g.config.Profile {
+			continue
+		}
+		path, ok := g.profileOutputPath(profile)
+		if !ok {
+			return "", false
+		}
+		lines, _, err := g.readEnvFileWithStructure(path)
+		if err != nil {
+			continue
+		}
+		for _, line := range lines {
+			if line.Type != LineTypeKeyValue {
+				continue
+			}
+			info, ok := templateInfo[line.Key]
+			if !ok || !info.HasPlaceholder || writesSecretFile(info.Value, name) {
+				continue
+			}
+			if value, ok := recoverPlaceholder(info.Value, unquoteValue(line.Value), name); ok {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// writesSecretFile checks if a template value writes the named placeholder to a secret file
+func writesSecretFile(templateValue, name string) bool {
+	placeholders, err := placeholdersIn(templateValue)
+	if err != nil {
+		return false
+	}
+	for _, p := range placeholders {
+		if p.name == name && p.file != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// recoverPlaceholder extracts the value a placeholder was given from a generated value
+// by matching it against the template value with every placeholder as a wildcard
+func recoverPlaceholder(templateValue, value, name string) (string, bool) {
+	templateValue = unquoteValue(templateValue)
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	var names []string
+	last := 0
+	for _, match := range placeholderRe.FindAllStringSubmatchIndex(templateValue, -1) {
+		if match[0] > 0 && templateValue[match[0]-1] == '\\' {
+			continue
+		}
+		p, err := parsePlaceholder(templateValue[match[2]:match[3]])
+		if err != nil {
+			return "", false
+		}
+		pattern.WriteString(regexp.QuoteMeta(strings.ReplaceAll(templateValue[last:match[0]], `\${`, "${")))
+		pattern.WriteString("(.+?)")
+		names = append(names, p.name)
+		last = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(strings.ReplaceAll(templateValue[last:], `\${`, "${")))
+	pattern.WriteString("$")
+
+	re, err := regexp.Compile(pattern.String())
+	if err != nil {
+		return "", false
+	}
+	match := re.FindStringSubmatch(value)
+	if match == nil {
+		return "", false
+	}
+	for i, n := range names {
+		if n == name {
+			return match[i+1], true
+		}
+	}
+	return "", false
+}
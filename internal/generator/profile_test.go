@@ -0,0 +1,217 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// profileTestTemplate scopes lines to profiles both inline and with sections
+const profileTestTemplate = `# Log level
+LOG_LEVEL=info
+LOG_LEVEL=debug  # @profile dev
+LOG_LEVEL=warn   # @profile staging,prod
+
+# @shared
+DB_PASSWORD=${db_password}
+API_KEY=${api_key}
+
+[profile test]
+# Only used by the test suite
+TEST_SEED=${seed:length=8,charset=numeric}
+[profile *]
+APP_NAME=demo
+`
+
+// TestProfileSelection tests which template lines apply to each profile
+func TestProfileSelection(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := writeTemplates(t, tempDir, [2]string{".env.example", profileTestTemplate})[0]
+
+	tests := map[string]string{
+		"":        "# Log level\nLOG_LEVEL=info\n\n# @shared\nDB_PASSWORD=${db_password}\nAPI_KEY=${api_key}\n\nAPP_NAME=demo",
+		"dev":     "# Log level\nLOG_LEVEL=debug\n\n# @shared\nDB_PASSWORD=${db_password}\nAPI_KEY=${api_key}\n\nAPP_NAME=demo",
+		"staging": "# Log level\nLOG_LEVEL=warn\n\n# @shared\nDB_PASSWORD=${db_password}\nAPI_KEY=${api_key}\n\nAPP_NAME=demo",
+		"test":    "# Log level\nLOG_LEVEL=info\n\n# @shared\nDB_PASSWORD=${db_password}\nAPI_KEY=${api_key}\n\n# Only used by the test suite\nTEST_SEED=${seed:length=8,charset=numeric}\nAPP_NAME=demo",
+	}
+	for profile, expected := range tests {
+		gen := New(Config{TemplatePath: templatePath, Profile: profile})
+		lines, _, err := gen.readTemplateFile()
+		if err != nil {
+			t.Fatalf("Failed to read template for %q: %v", profile, err)
+		}
+		if got := strings.Join(lines, "\n"); got != expected {
+			t.Errorf("Profile %q:\n%s\nexpected:\n%s", profile, got, expected)
+		}
+		if len(gen.templateSources) != len(lines) {
+			t.Errorf("Profile %q: expected a source for every line", profile)
+		}
+	}
+
+	profiles, err := New(Config{TemplatePath: templatePath}).Profiles()
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if strings.Join(profiles, ",") != "dev,prod,staging,test" {
+		t.Errorf("Unexpected profiles %v", profiles)
+	}
+
+	bad := writeTemplates(t, tempDir, [2]string{"bad.example", "A=1 # @profile dev/eu\n"})[0]
+	if _, err := New(Config{TemplatePath: bad}).Schema(); err == nil || !strings.Contains(err.Error(), bad+":1") {
+		t.Errorf("Expected an invalid profile error at %s:1, got %v", bad, err)
+	}
+}
+
+// TestProfileSharedValues tests that only @shared placeholders agree across profiles
+func TestProfileSharedValues(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := writeTemplates(t, tempDir, [2]string{".env.example", profileTestTemplate})[0]
+
+	generate := func(profile string, masterKey []byte) map[string]string {
+		t.Helper()
+		outputPath := filepath.Join(tempDir, ".env."+profile)
+		os.Remove(outputPath)
+		config := Config{TemplatePath: templatePath, OutputPath: outputPath, Profile: profile, MasterKey: masterKey}
+		if err := New(config).Generate(); err != nil {
+			t.Fatalf("Failed to generate %s: %v", outputPath, err)
+		}
+		return readEnv(t, outputPath)
+	}
+
+	// Random values: shared ones are recovered from the output of another profile
+	dev := generate("dev", nil)
+	staging := generate("staging", nil)
+	if dev["DB_PASSWORD"] != staging["DB_PASSWORD"] {
+		t.Errorf("Shared DB_PASSWORD differs: %q vs %q", dev["DB_PASSWORD"], staging["DB_PASSWORD"])
+	}
+	if dev["API_KEY"] == staging["API_KEY"] {
+		t.Error("API_KEY should be generated independently per profile")
+	}
+	if dev["LOG_LEVEL"] != "debug" || staging["LOG_LEVEL"] != "warn" {
+		t.Errorf("Unexpected log levels %q and %q", dev["LOG_LEVEL"], staging["LOG_LEVEL"])
+	}
+
+	// Derived values: the profile is part of the derivation unless the key is shared
+	masterKey := []byte("0123456789abcdef0123456789abcdef")
+	dev = generate("dev", masterKey)
+	staging = generate("staging", masterKey)
+	if dev["DB_PASSWORD"] != staging["DB_PASSWORD"] {
+		t.Error("Derived shared DB_PASSWORD should match across profiles")
+	}
+	if dev["API_KEY"] == staging["API_KEY"] {
+		t.Error("Derived API_KEY should differ across profiles")
+	}
+}
+
+// TestProfileSharedSecretFile tests that a shared file= placeholder keeps its secret when
+// another profile is generated, rather than taking the path from the other output
+func TestProfileSharedSecretFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := writeTemplates(t, tempDir, [2]string{".env.example", `# @shared
+DB_PASSWORD_FILE=${db_pw:file=./secrets/db_pw}
+LOG_LEVEL=debug # @profile dev
+LOG_LEVEL=warn  # @profile staging
+`})[0]
+	secretPath := filepath.Join(tempDir, "secrets", "db_pw")
+
+	var secrets []string
+	for _, profile := range []string{"dev", "staging"} {
+		outputPath := filepath.Join(tempDir, ".env."+profile)
+		if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath, Profile: profile}).Generate(); err != nil {
+			t.Fatalf("Failed to generate %s: %v", outputPath, err)
+		}
+		if env := readEnv(t, outputPath); env["DB_PASSWORD_FILE"] != "./secrets/db_pw" {
+			t.Errorf("Expected the path of the secret file, got %q", env["DB_PASSWORD_FILE"])
+		}
+		content, _ := os.ReadFile(secretPath)
+		secrets = append(secrets, string(content))
+	}
+
+	if secrets[0] == "" || secrets[1] != secrets[0] {
+		t.Errorf("Expected both profiles to keep the generated secret, got %q", secrets)
+	}
+	if strings.Contains(secrets[1], "secrets/db_pw") {
+		t.Errorf("The secret was replaced by the path of the secret file: %q", secrets[1])
+	}
+}
+
+// TestProfileOutputPath tests naming the outputs of other profiles after the current one
+func TestProfileOutputPath(t *testing.T) {
+	gen := New(Config{OutputPath: filepath.Join("config", ".env.dev.json"), Profile: "dev"})
+	if path, ok := gen.profileOutputPath("staging"); !ok || path != filepath.Join("config", ".env.staging.json") {
+		t.Errorf("Unexpected staging path %q", path)
+	}
+	if path, ok := gen.profileOutputPath(""); !ok || path != filepath.Join("config", ".env.json") {
+		t.Errorf("Unexpected default path %q", path)
+	}
+	if _, ok := New(Config{OutputPath: "custom.env", Profile: "dev"}).profileOutputPath("staging"); ok {
+		t.Error("Outputs not named after the profile should not be guessed")
+	}
+}
+
+// TestLintProfiles tests that lint checks the lines of every profile
+func TestLintProfiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := writeTemplates(t, tempDir, [2]string{".env.example", "EMPTY=${}\nA=${} # @profile dev\n[profile prod]\nB=${}\n"})[0]
+	diagnostics, err := New(Config{TemplatePath: templatePath}).Lint()
+	if err != nil {
+		t.Fatalf("Failed to lint template: %v", err)
+	}
+	var lines []int
+	for _, d := range diagnostics {
+		lines = append(lines, d.Line)
+	}
+	if len(lines) != 3 || lines[0] != 1 || lines[1] != 2 || lines[2] != 4 {
+		t.Errorf("Expected diagnostics on lines 1, 2 and 4, got %v", diagnostics)
+	}
+}
+
+// TestBracketLinesWithoutProfiles tests that bracketed lines other than [profile name] headers
+// don't scope the lines after them
+func TestBracketLinesWithoutProfiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	template := "[database]\nDB_HOST=localhost\nDB_PASSWORD=${db_password}\n\n[web server]\nPORT=8080\n"
+	templatePath := writeTemplates(t, tempDir, [2]string{".env.example", template})[0]
+	outputPath := filepath.Join(tempDir, ".env")
+	gen := New(Config{TemplatePath: templatePath, OutputPath: outputPath})
+	if err := gen.Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	env := readEnv(t, outputPath)
+	if env["DB_HOST"] != "localhost" || len(env["DB_PASSWORD"]) != DefaultValueLength || env["PORT"] != "8080" {
+		t.Errorf("Expected every key of the template, got %v", env)
+	}
+	if data, _ := os.ReadFile(outputPath); !strings.Contains(string(data), "[database]\n") || !strings.Contains(string(data), "[web server]\n") {
+		t.Errorf("Expected the bracketed lines to be kept:\n%s", data)
+	}
+	if profiles, err := gen.Profiles(); err != nil || len(profiles) != 0 {
+		t.Errorf("Expected no profiles, got %v, %v", profiles, err)
+	}
+}
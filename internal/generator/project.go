@@ -0,0 +1,595 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ProjectConfigNames are the file names of project configuration files
+var ProjectConfigNames = []string{".genenv.toml", ".genenv.yaml", ".genenv.yml"}
+
+// ProjectSettingNames are the top-level settings of a project configuration file, which
+// command-line flags and GENENV_* environment variables override
+var ProjectSettingNames = []string{"template", "output", "length", "charset", "dialect", "mode"}
+
+// Hook stages of a project configuration file
+const (
+	HookPre  = "pre"  // Runs before generating
+	HookPost = "post" // Runs after generating successfully
+)
+
+// Preset is a named set of generator options, used by ${name:preset=...} placeholders
+type Preset struct {
+	Length  int
+	Charset CharsetType
+}
+
+// String describes the options of the preset
+func (p Preset) String() string {
+	return describeSpec(placeholder{length: p.Length, charset: p.Charset})
+}
+
+// ProjectSetting is a top-level setting of a project configuration file
+type ProjectSetting struct {
+	Value string
+	Line  int
+}
+
+// ProjectConfig is a project configuration file
+// Settings are validated by the command that applies them; presets, key overrides and hooks
+// are validated when the file is loaded.
+type ProjectConfig struct {
+	Path     string
+	Settings map[string]ProjectSetting
+	Presets  map[string]Preset
+	Keys     map[string]Preset // Generator options for the placeholders of a key
+	Hooks    map[string][]string
+
+	// Lines locates presets, keys and hooks, e.g. Lines["presets.pin"]
+	Lines map[string]int
+}
+
+// Source formats the location of a line of the file
+func (c *ProjectConfig) Source(line int) string {
+	return fmt.Sprintf("%s:%d", c.Path, line)
+}
+
+// FindRepoRoot returns the nearest directory at or above dir holding a .git, or an empty path
+// if dir is not in a repository
+func FindRepoRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for current := abs; ; current = filepath.Dir(current) {
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, nil
+		}
+		if filepath.Dir(current) == current {
+			return "", nil
+		}
+	}
+}
+
+// FindProjectConfig looks for a project configuration file in dir and its parents, and
+// returns an empty path if there is none
+func FindProjectConfig(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for current := abs; ; current = filepath.Dir(current) {
+		var found []string
+		for _, name := range ProjectConfigNames {
+			if _, err := os.Stat(filepath.Join(current, name)); err == nil {
+				found = append(found, filepath.Join(current, name))
+			}
+		}
+		switch {
+		case len(found) > 1:
+			return "", fmt.Errorf("found several project configuration files: %s; keep one", strings.Join(found, ", "))
+		case len(found) == 1:
+			return found[0], nil
+		}
+		if filepath.Dir(current) == current {
+			return "", nil
+		}
+	}
+}
+
+// LoadProjectConfig reads a project configuration file in the TOML or YAML subset genenv
+// understands, chosen by its extension
+func LoadProjectConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("project configuration file %s does not exist", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project configuration: %w", err)
+	}
+
+	var entries []projectEntry
+	switch filepath.Ext(path) {
+	case ".toml":
+		entries, err = parseProjectTOML(string(data))
+	case ".yaml", ".yml":
+		entries, err = parseProjectYAML(string(data))
+	default:
+		return nil, fmt.Errorf("unknown project configuration format %s; use .toml, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+
+	config := &ProjectConfig{
+		Path:     path,
+		Settings: make(map[string]ProjectSetting),
+		Presets:  make(map[string]Preset),
+		Keys:     make(map[string]Preset),
+		Hooks:    make(map[string][]string),
+		Lines:    make(map[string]int),
+	}
+	if err := config.decode(entries); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// projectEntry is a value of a project configuration file with its dotted key
+type projectEntry struct {
+	key    string
+	text   string
+	list   []string
+	isList bool
+	line   int
+}
+
+// keyPresetName is the key override option naming the preset it starts from
+const keyPresetName = "preset"
+
+// decode fills the config from the entries of its file
+func (c *ProjectConfig) decode(entries []projectEntry) error {
+	keyPresets := make(map[string]projectEntry)
+	keyOptions := make(map[string][]projectEntry)
+
+	for _, entry := range entries {
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("%s: %s", c.Source(entry.line), fmt.Sprintf(format, args...))
+		}
+		parts := strings.Split(entry.key, ".")
+		if entry.isList && parts[0] != "hooks" {
+			return fail("%s takes a single value, not a list", entry.key)
+		}
+
+		switch {
+		case len(parts) == 1 && slices.Contains(ProjectSettingNames, parts[0]):
+			c.Settings[parts[0]] = ProjectSetting{Value: entry.text, Line: entry.line}
+
+		case len(parts) == 2 && parts[0] == "hooks" && (parts[1] == HookPre || parts[1] == HookPost):
+			commands := entry.list
+			if !entry.isList {
+				commands = []string{entry.text}
+			}
+			c.Hooks[parts[1]] = append(c.Hooks[parts[1]], commands...)
+			c.Lines[entry.key] = entry.line
+
+		case len(parts) == 3 && parts[0] == "presets":
+			preset := c.Presets[parts[1]]
+			if err := preset.set(parts[2], entry.text); err != nil {
+				return fail("%v", err)
+			}
+			c.Presets[parts[1]] = preset
+			if _, ok := c.Lines["presets."+parts[1]]; !ok {
+				c.Lines["presets."+parts[1]] = entry.line
+			}
+
+		case len(parts) == 3 && parts[0] == "keys":
+			if parts[2] == keyPresetName {
+				keyPresets[parts[1]] = entry
+			} else {
+				keyOptions[parts[1]] = append(keyOptions[parts[1]], entry)
+			}
+			if _, ok := c.Lines["keys."+parts[1]]; !ok {
+				c.Lines["keys."+parts[1]] = entry.line
+			}
+
+		default:
+			return fail("unknown setting %s", entry.key)
+		}
+	}
+
+	// Key overrides start from their preset, whatever order the options come in
+	for key := range c.Lines {
+		name, ok := strings.CutPrefix(key, "keys.")
+		if !ok {
+			continue
+		}
+		var preset Preset
+		if entry, ok := keyPresets[name]; ok {
+			if preset, ok = c.Presets[entry.text]; !ok {
+				return fmt.Errorf("%s: %s uses unknown preset %q", c.Source(entry.line), name, entry.text)
+			}
+		}
+		for _, entry := range keyOptions[name] {
+			if err := preset.set(strings.TrimPrefix(entry.key, key+"."), entry.text); err != nil {
+				return fmt.Errorf("%s: %v", c.Source(entry.line), err)
+			}
+		}
+		c.Keys[name] = preset
+	}
+	return nil
+}
+
+// set sets an option of a preset from its text
+func (p *Preset) set(option, text string) error {
+	switch option {
+	case "length":
+		length, err := strconv.Atoi(text)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("length must be a positive number, got %q", text)
+		}
+		p.Length = length
+	case "charset":
+		if !validCharset(CharsetType(text)) {
+			return fmt.Errorf("unknown charset %q", text)
+		}
+		p.Charset = CharsetType(text)
+	default:
+		return fmt.Errorf("unknown generator option %s; use length or charset", option)
+	}
+	return nil
+}
+
+// parseProjectTOML reads the TOML subset of project configuration files: tables, and keys
+// with string, integer, boolean or string array values
+func parseProjectTOML(data string) ([]projectEntry, error) {
+	var entries []projectEntry
+	table := ""
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripConfigComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if strings.HasPrefix(line, "[[") || !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("%d: invalid table header %s", lineNumber, line)
+			}
+			name, err := configKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", lineNumber, err)
+			}
+			table = name
+			continue
+		}
+
+		rawKey, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%d: expected key = value", lineNumber)
+		}
+		key, err := configKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", lineNumber, err)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		value = strings.TrimSpace(value)
+
+		// Arrays may span lines until their closing bracket
+		for strings.HasPrefix(value, "[") && !strings.HasSuffix(value, "]") && i+1 < len(lines) {
+			i++
+			value += " " + strings.TrimSpace(stripConfigComment(lines[i]))
+		}
+
+		entry := projectEntry{key: key, line: lineNumber}
+		if strings.HasPrefix(value, "[") {
+			entry.isList = true
+			entry.list, err = configList(value)
+		} else {
+			entry.text, err = tomlScalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", lineNumber, key, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// tomlScalar returns the text of a TOML string, integer or boolean
+func tomlScalar(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'"):
+		return configString(value)
+	case value == "true" || value == "false":
+		return value, nil
+	}
+	if _, err := strconv.ParseInt(strings.ReplaceAll(value, "_", ""), 0, 64); err != nil {
+		return "", fmt.Errorf("invalid value %s; quote strings", value)
+	}
+	return strings.ReplaceAll(value, "_", ""), nil
+}
+
+// parseProjectYAML reads the YAML subset of project configuration files: nested block
+// mappings of scalars, and lists of scalars in block or flow style
+func parseProjectYAML(data string) ([]projectEntry, error) {
+	type level struct {
+		indent int
+		key    string
+	}
+	var entries []projectEntry
+	var stack []level
+	listIndex := -1 // Entry the block list items below the current key go to
+
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		lineNumber := i + 1
+		line := strings.TrimRight(stripConfigComment(raw), " ")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || content == "---" {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("%d: indent with spaces, not tabs", lineNumber)
+		}
+		indent := len(line) - len(content)
+
+		if item, ok := strings.CutPrefix(content, "-"); ok && (item == "" || item[0] == ' ') {
+			if listIndex < 0 || len(stack) == 0 || indent < stack[len(stack)-1].indent {
+				return nil, fmt.Errorf("%d: list item outside a list", lineNumber)
+			}
+			text, err := yamlConfigScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", lineNumber, err)
+			}
+			entries[listIndex].list = append(entries[listIndex].list, text)
+			continue
+		}
+
+		rawKey, value, ok := strings.Cut(content, ":")
+		if !ok || (value != "" && value[0] != ' ') {
+			return nil, fmt.Errorf("%d: expected key: value", lineNumber)
+		}
+		key, err := configKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w", lineNumber, err)
+		}
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) > 0 {
+			key = stack[len(stack)-1].key + "." + key
+		}
+		value = strings.TrimSpace(value)
+		listIndex = -1
+
+		entry := projectEntry{key: key, line: lineNumber}
+		switch {
+		case value == "":
+			// A mapping or a block list follows; the entry only stays if list items do
+			stack = append(stack, level{indent: indent, key: key})
+			entry.isList = true
+			listIndex = len(entries)
+			entries = append(entries, entry)
+			continue
+		case strings.HasPrefix(value, "["):
+			entry.isList = true
+			entry.list, err = configList(value)
+		default:
+			entry.text, err = yamlConfigScalar(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", lineNumber, key, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	// Drop the mapping openers that got no list items
+	kept := entries[:0]
+	for _, entry := range entries {
+		if !entry.isList || entry.list != nil || !hasChildEntry(entries, entry.key) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept, nil
+}
+
+// hasChildEntry checks if any entry is nested below key
+func hasChildEntry(entries []projectEntry, key string) bool {
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.key, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlConfigScalar returns the text of a YAML scalar
+func yamlConfigScalar(value string) (string, error) {
+	if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+		return configString(value)
+	}
+	return value, nil
+}
+
+// configKey returns a dotted key with its quoted parts unquoted
+func configKey(raw string) (string, error) {
+	var parts []string
+	for _, part := range strings.Split(strings.TrimSpace(raw), ".") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, `"`) || strings.HasPrefix(part, "'") {
+			unquoted, err := configString(part)
+			if err != nil {
+				return "", err
+			}
+			part = unquoted
+		}
+		if part == "" {
+			return "", fmt.Errorf("invalid key %q", strings.TrimSpace(raw))
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// configString unquotes a double-quoted string with escapes or a single-quoted literal
+func configString(value string) (string, error) {
+	if len(value) < 2 || value[len(value)-1] != value[0] {
+		return "", fmt.Errorf("unclosed string %s", value)
+	}
+	if value[0] == '\'' {
+		// YAML escapes a single quote by doubling it; TOML literals can't contain one
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", value)
+	}
+	return unquoted, nil
+}
+
+// configList reads a flow list of strings, e.g. ["a", "b"]
+func configList(value string) ([]string, error) {
+	if !strings.HasSuffix(value, "]") {
+		return nil, errors.New("unclosed list")
+	}
+	items := []string{}
+	inner := strings.TrimSpace(value[1 : len(value)-1])
+	for inner != "" {
+		var item string
+		if inner[0] == '"' || inner[0] == '\'' {
+			end := configClosingQuote(inner)
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed string %s", inner)
+			}
+			text, err := configString(inner[:end+1])
+			if err != nil {
+				return nil, err
+			}
+			item, inner = text, strings.TrimSpace(inner[end+1:])
+		} else {
+			end := strings.IndexByte(inner, ',')
+			if end < 0 {
+				end = len(inner)
+			}
+			item, inner = strings.TrimSpace(inner[:end]), inner[end:]
+		}
+		items = append(items, item)
+		if inner != "" {
+			if inner[0] != ',' {
+				return nil, fmt.Errorf("expected , between list items")
+			}
+			inner = strings.TrimSpace(inner[1:])
+		}
+	}
+	return items, nil
+}
+
+// configClosingQuote returns the index of the quote closing the string at the start of s, or -1
+func configClosingQuote(s string) int {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripConfigComment removes a # comment that is not inside a string
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// applyPresets writes the options of presets and key overrides into the placeholders of a
+// template, so the rest of the generator sees them as options given in place
+// Options given in a placeholder win over its preset. Key overrides only apply to
+// placeholders without generator options of their own.
+func (g *Generator) applyPresets(layer templateLayer) error {
+	for i, line := range layer.lines {
+		if !isTemplateKeyLine(layer.lines, i) || !strings.Contains(line, "${") {
+			continue
+		}
+		key, _, _ := parseKeyValue(line)
+		override, hasOverride := g.config.KeyOverrides[key]
+
+		var rewritten strings.Builder
+		last := 0
+		for _, match := range placeholderRe.FindAllStringSubmatchIndex(line, -1) {
+			if match[0] > 0 && line[match[0]-1] == '\\' {
+				continue
+			}
+			p, err := parsePlaceholder(line[match[2]:match[3]])
+			if err != nil {
+				// Malformed placeholders are reported when the value is generated
+				continue
+			}
+			var preset Preset
+			switch {
+			case p.preset != "":
+				var ok bool
+				if preset, ok = g.config.Presets[p.preset]; !ok {
+					return fmt.Errorf("%s: placeholder %s uses unknown preset %q", layer.sources[i], line[match[0]:match[1]], p.preset)
+				}
+			case hasOverride && p.length == 0 && p.charset == "":
+				preset = override
+			default:
+				continue
+			}
+			if p.length == 0 {
+				p.length = preset.Length
+			}
+			if p.charset == "" {
+				p.charset = preset.Charset
+			}
+			rewritten.WriteString(line[last:match[0]])
+			rewritten.WriteString(formatPlaceholder(p))
+			last = match[1]
+		}
+		rewritten.WriteString(line[last:])
+		layer.lines[i] = rewritten.String()
+	}
+	return nil
+}
+
+// formatPlaceholder writes a placeholder back as template text, without its preset
+func formatPlaceholder(p placeholder) string {
+	var options []string
+	if p.file != "" {
+		options = append(options, "file="+p.file)
+	}
+	if spec := describeSpec(p); spec != "" {
+		options = append(options, spec)
+	}
+	if len(options) == 0 {
+		return "${" + p.name + "}"
+	}
+	return "${" + p.name + ":" + strings.Join(options, ",") + "}"
+}
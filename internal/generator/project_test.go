@@ -0,0 +1,222 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestLoadProjectConfig tests that the TOML and YAML forms of a project configuration agree
+func TestLoadProjectConfig(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"toml/.genenv.toml", `# Defaults
+template = "env/.env.example"
+length = 32 # inline comment
+mode = '0640'
+
+[presets.pin]
+length = 6
+charset = "numeric"
+
+[keys]
+API_TOKEN.preset = "pin"
+API_TOKEN.length = 8
+
+[hooks]
+pre = "echo \"# not a comment\""
+post = [
+  "make restart",
+  "echo done",
+]
+`},
+		[2]string{"yaml/.genenv.yaml", `# Defaults
+template: env/.env.example
+length: 32 # inline comment
+mode: '0640'
+
+presets:
+  pin:
+    length: 6
+    charset: numeric
+
+keys:
+  API_TOKEN:
+    length: 8
+    preset: pin
+
+hooks:
+  pre: 'echo "# not a comment"'
+  post: [make restart, "echo done"]
+`},
+	)
+
+	for _, path := range paths {
+		config, err := LoadProjectConfig(path)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %v", path, err)
+		}
+		if config.Settings["template"].Value != "env/.env.example" || config.Settings["length"] != (ProjectSetting{Value: "32", Line: 3}) || config.Settings["mode"].Value != "0640" {
+			t.Errorf("%s: unexpected settings %+v", path, config.Settings)
+		}
+		if config.Presets["pin"] != (Preset{Length: 6, Charset: CharsetNumeric}) {
+			t.Errorf("%s: unexpected presets %+v", path, config.Presets)
+		}
+		// The key keeps the charset of its preset and overrides the length, whatever the order
+		if config.Keys["API_TOKEN"] != (Preset{Length: 8, Charset: CharsetNumeric}) {
+			t.Errorf("%s: unexpected key overrides %+v", path, config.Keys)
+		}
+		expectedHooks := map[string][]string{HookPre: {`echo "# not a comment"`}, HookPost: {"make restart", "echo done"}}
+		if !reflect.DeepEqual(config.Hooks, expectedHooks) {
+			t.Errorf("%s: unexpected hooks %q", path, config.Hooks)
+		}
+		if config.Lines["presets.pin"] != 7 && config.Lines["presets.pin"] != 8 {
+			t.Errorf("%s: unexpected line of presets.pin: %d", path, config.Lines["presets.pin"])
+		}
+	}
+}
+
+// TestLoadProjectConfigErrors tests that invalid configurations are reported with their line
+func TestLoadProjectConfigErrors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{".genenv.toml", "length = 8\nlenght = 9\n", ".genenv.toml:2: unknown setting lenght"},
+		{".genenv.toml", "[presets.pin]\nlength = 0\n", ".genenv.toml:2: length must be a positive number"},
+		{".genenv.toml", "[keys.TOKEN]\npreset = \"nope\"\n", `.genenv.toml:2: TOKEN uses unknown preset "nope"`},
+		{".genenv.toml", "output = .env\n", ".genenv.toml:1: output: invalid value .env; quote strings"},
+		{".genenv.toml", "[[hooks]]\n", ".genenv.toml:1: invalid table header"},
+		{".genenv.yaml", "presets:\n  pin:\n    charset: hex\n", `.genenv.yaml:3: unknown charset "hex"`},
+		{".genenv.yaml", "output: [a, b]\n", ".genenv.yaml:1: output takes a single value, not a list"},
+		{".genenv.yaml", "- item\n", ".genenv.yaml:1: list item outside a list"},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(tempDir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		_, err := LoadProjectConfig(path)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected error containing %q for %q, got %v", tt.expected, tt.content, err)
+		}
+	}
+}
+
+// TestFindProjectConfig tests discovering the project configuration upward
+func TestFindProjectConfig(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	root, _ := filepath.EvalSymlinks(tempDir)
+	service := filepath.Join(root, "services", "api")
+	os.MkdirAll(service, 0755)
+
+	if path, err := FindProjectConfig(service); err != nil || path != "" {
+		t.Errorf("Expected no configuration, got %q, %v", path, err)
+	}
+
+	os.WriteFile(filepath.Join(root, ".genenv.yml"), []byte("length: 8\n"), 0644)
+	if path, _ := FindProjectConfig(service); path != filepath.Join(root, ".genenv.yml") {
+		t.Errorf("Expected the configuration of the root, got %q", path)
+	}
+
+	os.WriteFile(filepath.Join(root, ".genenv.toml"), []byte("length = 8\n"), 0644)
+	if _, err := FindProjectConfig(service); err == nil || !strings.Contains(err.Error(), "several project configuration files") {
+		t.Errorf("Expected an error for two configurations in one directory, got %v", err)
+	}
+}
+
+// TestPresets tests that presets and key overrides set the options of placeholders
+func TestPresets(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir, [2]string{".env.example", `PIN=${pin:preset=pin}
+LONG_PIN=${long_pin:preset=pin,length=10}
+API_TOKEN=${api_token}
+OWN_TOKEN=${own:length=3}
+ESCAPED=\${raw:preset=nope}
+OTHER=${other}
+`})
+	config := Config{
+		TemplatePath: paths[0],
+		OutputPath:   filepath.Join(tempDir, ".env"),
+		ValueLength:  24,
+		Charset:      CharsetAlphanumeric,
+		Presets:      map[string]Preset{"pin": {Length: 6, Charset: CharsetNumeric}},
+		KeyOverrides: map[string]Preset{"API_TOKEN": {Length: 12, Charset: CharsetUppercase}, "OWN_TOKEN": {Length: 40}},
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+
+	env := readEnv(t, config.OutputPath)
+	expected := map[string]struct {
+		length  int
+		charset string
+	}{
+		"PIN":       {6, "0123456789"},
+		"LONG_PIN":  {10, "0123456789"},
+		"API_TOKEN": {12, "ABCDEFGHIJKLMNOPQRSTUVWXYZ"},
+		"OWN_TOKEN": {3, ""},
+		"OTHER":     {24, ""},
+	}
+	for key, want := range expected {
+		value := env[key]
+		if len(value) != want.length || (want.charset != "" && strings.Trim(value, want.charset) != "") {
+			t.Errorf("Expected %s to have %d characters from %q, got %q", key, want.length, want.charset, value)
+		}
+	}
+	if env["ESCAPED"] != "${raw:preset=nope}" {
+		t.Errorf("Expected the escaped placeholder to stay, got %q", env["ESCAPED"])
+	}
+
+	config.Presets = nil
+	if err := New(config).Generate(); err == nil || !strings.Contains(err.Error(), `uses unknown preset "pin"`) {
+		t.Errorf("Expected an error for an unknown preset, got %v", err)
+	}
+}
+
+// TestFindRepoRoot tests finding the repository a directory belongs to
+func TestFindRepoRoot(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	root, _ := filepath.EvalSymlinks(tempDir)
+	service := filepath.Join(root, "services", "api")
+	os.MkdirAll(service, 0755)
+
+	if path, err := FindRepoRoot(service); err != nil || path != "" {
+		t.Errorf("Expected no repository, got %q, %v", path, err)
+	}
+
+	os.MkdirAll(filepath.Join(root, ".git"), 0755)
+	if path, _ := FindRepoRoot(service); path != root {
+		t.Errorf("Expected the repository at %s, got %q", root, path)
+	}
+}
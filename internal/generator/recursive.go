@@ -0,0 +1,257 @@
+package generator
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// DefaultTemplatePattern matches the templates FindTemplates looks for by default
+const DefaultTemplatePattern = ".env.example"
+
+// ignoreRule is a pattern of a .gitignore file
+type ignoreRule struct {
+	base     string // Directory of the .gitignore relative to the root, slash-separated; empty for the root
+	pattern  *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool // Matches paths relative to base rather than names
+}
+
+// matches checks if the rule applies to a path relative to the root
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(rel, r.base+"/"); !ok {
+			return false
+		}
+	}
+	if !r.anchored {
+		rel = path.Base(rel)
+	}
+	return r.pattern.MatchString(rel)
+}
+
+// parseIgnoreFile reads the rules of a .gitignore file; a missing file has none
+func parseIgnoreFile(file, base string) ([]ignoreRule, error) {
+	f, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule := ignoreRule{base: base}
+		if negated, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate, line = true, negated
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if trimmed, ok := strings.CutSuffix(line, "/"); ok {
+			rule.dirOnly, line = true, trimmed
+		}
+		// A slash anywhere but at the end ties the pattern to the directory of the .gitignore
+		if strings.Contains(line, "/") {
+			rule.anchored, line = true, strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if rule.pattern, err = globRegexp(line); err != nil {
+			return nil, fmt.Errorf("%s: invalid pattern %q: %w", file, scanner.Text(), err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// globRegexp compiles a glob with gitignore semantics: * and ? don't match /, and ** matches
+// any number of directories
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			pattern.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			pattern.WriteString(".*")
+			i++
+		case c == '*':
+			pattern.WriteString("[^/]*")
+		case c == '?':
+			pattern.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, errors.New("unclosed [")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			pattern.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			pattern.WriteString(regexp.QuoteMeta(string(glob[i+1])))
+			i++
+		default:
+			pattern.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	pattern.WriteString("$")
+	return regexp.Compile(pattern.String())
+}
+
+// ignored checks if the last rule matching a path ignores it
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	ignore := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			ignore = !rule.negate
+		}
+	}
+	return ignore
+}
+
+// FindTemplates walks the tree under root and returns the templates matching pattern, in
+// lexical order
+// The pattern is a glob matched against file names, or against paths relative to root when
+// it contains a slash. Files and directories ignored by .gitignore files, and .git itself,
+// are skipped.
+func FindTemplates(root, pattern string) ([]string, error) {
+	if pattern == "" {
+		pattern = DefaultTemplatePattern
+	}
+	matcher, err := globRegexp(strings.TrimPrefix(pattern, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid template pattern %q: %w", pattern, err)
+	}
+	byPath := strings.Contains(pattern, "/")
+
+	var templates []string
+	var rules []ignoreRule
+	err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && (entry.Name() == ".git" || ignored(rules, rel, true)) {
+				return filepath.SkipDir
+			}
+			base := rel
+			if base == "." {
+				base = ""
+			}
+			dirRules, err := parseIgnoreFile(filepath.Join(file, ".gitignore"), base)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, dirRules...)
+			return nil
+		}
+
+		if !entry.Type().IsRegular() || ignored(rules, rel, false) {
+			return nil
+		}
+		name := entry.Name()
+		if byPath {
+			name = rel
+		}
+		if matcher.MatchString(name) {
+			templates = append(templates, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// Result is the outcome of generating one template with GenerateAll
+type Result struct {
+	Config     Config
+	Err        error
+	Warnings   []string
+	BackupPath string
+}
+
+// GenerateAll generates every config with at most jobs generators running at once and
+// returns their results in the order of configs
+// All configs share one set of shared values. The values shared placeholders already have in
+// existing outputs are collected before anything is generated, so new outputs reuse them.
+func GenerateAll(configs []Config, jobs int) []Result {
+	shared := NewSharedValues()
+	generators := make([]*Generator, len(configs))
+	for i, config := range configs {
+		if config.Shared == nil {
+			config.Shared = shared
+		}
+		generators[i] = New(config)
+	}
+
+	results := make([]Result, len(configs))
+	runAll(len(configs), jobs, func(i int) {
+		results[i] = Result{Config: generators[i].config}
+		results[i].Err = generators[i].collectSharedValues()
+	})
+	runAll(len(configs), jobs, func(i int) {
+		if results[i].Err != nil {
+			return
+		}
+		g := generators[i]
+		results[i].Err = g.Generate()
+		results[i].Warnings = g.Warnings()
+		results[i].BackupPath = g.BackupPath()
+	})
+	return results
+}
+
+// runAll calls run for 0..n-1 with at most jobs calls running at once
+func runAll(n, jobs int, run func(i int)) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				run(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+}
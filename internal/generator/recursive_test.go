@@ -0,0 +1,131 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestGlobRegexp tests gitignore-style glob matching
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{".env.example", ".env.example", true},
+		{"*.example", ".env.example", true},
+		{"*.example", "api/.env.example", false},
+		{"**/.env.example", ".env.example", true},
+		{"**/.env.example", "services/api/.env.example", true},
+		{"services/**", "services/api/.env", true},
+		{"a?c", "abc", true},
+		{"a?c", "a/c", false},
+		{"[!a]bc", "xbc", true},
+		{"[!a]bc", "abc", false},
+		{`\#file`, "#file", true},
+	}
+
+	for _, test := range tests {
+		re, err := globRegexp(test.glob)
+		if err != nil {
+			t.Errorf("globRegexp(%q) failed: %v", test.glob, err)
+			continue
+		}
+		if re.MatchString(test.path) != test.matches {
+			t.Errorf("globRegexp(%q) matching %q = %v, expected %v", test.glob, test.path, !test.matches, test.matches)
+		}
+	}
+}
+
+// TestFindTemplates tests walking a tree for templates while respecting .gitignore files
+func TestFindTemplates(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	writeTemplates(t, tempDir,
+		[2]string{".gitignore", "node_modules/\n/build\n*.local.example\n"},
+		[2]string{".env.example", "A=1\n"},
+		[2]string{"api/.env.example", "A=1\n"},
+		[2]string{"api/.env.local.example", "A=1\n"},
+		[2]string{"web/.gitignore", "fixtures/\n!keep/\n"},
+		[2]string{"web/fixtures/.env.example", "A=1\n"},
+		[2]string{"web/keep/.env.example", "A=1\n"},
+		[2]string{"node_modules/pkg/.env.example", "A=1\n"},
+		[2]string{"build/.env.example", "A=1\n"},
+		[2]string{"tools/build/.env.example", "A=1\n"},
+		[2]string{".git/.env.example", "A=1\n"},
+	)
+
+	templates, err := FindTemplates(tempDir, "")
+	if err != nil {
+		t.Fatalf("FindTemplates failed: %v", err)
+	}
+	var found []string
+	for _, template := range templates {
+		rel, _ := filepath.Rel(tempDir, template)
+		found = append(found, filepath.ToSlash(rel))
+	}
+	expected := []string{".env.example", "api/.env.example", "tools/build/.env.example", "web/keep/.env.example"}
+	if !slices.Equal(found, expected) {
+		t.Errorf("FindTemplates() = %v, expected %v", found, expected)
+	}
+
+	// A pattern with a slash matches paths relative to the root
+	templates, err = FindTemplates(tempDir, "api/*.example")
+	if err != nil {
+		t.Fatalf("FindTemplates failed: %v", err)
+	}
+	if len(templates) != 1 || filepath.Base(filepath.Dir(templates[0])) != "api" {
+		t.Errorf("Expected api/.env.example alone, got %v", templates)
+	}
+}
+
+// TestGenerateAllShared tests that shared placeholders get one value across templates
+func TestGenerateAllShared(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"api/.env.example", "JWT_SECRET=${shared:jwt_secret}\nDB_PASSWORD=${db_password}\n"},
+		[2]string{"gateway/.env.example", "JWT=Bearer-${shared:jwt_secret}\nDB_PASSWORD=${db_password}\n"},
+		[2]string{"broken/.env.example", "BAD=${x:length=0}\n"},
+	)
+	configFor := func(template string) Config {
+		return Config{TemplatePath: template, OutputPath: filepath.Join(filepath.Dir(template), ".env")}
+	}
+
+	results := GenerateAll([]Config{configFor(paths[0]), configFor(paths[1]), configFor(paths[2])}, 2)
+	if results[0].Err != nil || results[1].Err != nil {
+		t.Fatalf("Failed to generate: %v, %v", results[0].Err, results[1].Err)
+	}
+	if results[2].Err == nil {
+		t.Errorf("Expected the broken template to fail")
+	}
+
+	api := readEnv(t, results[0].Config.OutputPath)
+	gateway := readEnv(t, results[1].Config.OutputPath)
+	if api["JWT_SECRET"] == "" || gateway["JWT"] != "Bearer-"+api["JWT_SECRET"] {
+		t.Errorf("Expected the same JWT secret, got %q and %q", api["JWT_SECRET"], gateway["JWT"])
+	}
+	if api["DB_PASSWORD"] == gateway["DB_PASSWORD"] {
+		t.Errorf("Placeholders outside the shared namespace should differ per template")
+	}
+
+	// A template added later reuses the shared value of the existing outputs
+	newPaths := writeTemplates(t, tempDir, [2]string{"worker/.env.example", "JWT_SECRET=${shared:jwt_secret}\n"})
+	results = GenerateAll([]Config{configFor(newPaths[0]), configFor(paths[0])}, 4)
+	if results[0].Err != nil {
+		t.Fatalf("Failed to generate: %v", results[0].Err)
+	}
+	if worker := readEnv(t, results[0].Config.OutputPath); worker["JWT_SECRET"] != api["JWT_SECRET"] {
+		t.Errorf("Expected the existing JWT secret %q, got %q", api["JWT_SECRET"], worker["JWT_SECRET"])
+	}
+}
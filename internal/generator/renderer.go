@@ -0,0 +1,269 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format selects the layout of the output file
+type Format string
+
+const (
+	// FormatDotenv writes a KEY=value env file, preserving existing lines exactly
+	FormatDotenv Format = "dotenv"
+	// FormatJSON writes a JSON object of keys to values
+	FormatJSON Format = "json"
+	// FormatYAML writes a YAML mapping of keys to values
+	FormatYAML Format = "yaml"
+	// FormatK8sSecret writes a Kubernetes Secret holding every key
+	FormatK8sSecret Format = "k8s-secret"
+	// FormatK8sSplit writes generated secrets to a Secret and literal values to a ConfigMap
+	FormatK8sSplit Format = "k8s-split"
+	// FormatShell writes a POSIX shell script of export statements
+	FormatShell Format = "sh"
+	// FormatFish writes a fish script of set -gx statements
+	FormatFish Format = "fish"
+	// FormatPowerShell writes a PowerShell script of $env: assignments
+	FormatPowerShell Format = "powershell"
+)
+
+// Renderer converts between env lines and an output file format
+// Generate merges in terms of env lines, so a renderer only has to translate at the edges:
+// Parse turns an existing output file into env lines and Render turns the merged lines back.
+type Renderer interface {
+	// Render encodes the merged env lines into the output file content
+	Render(lines []EnvLine) ([]byte, error)
+	// Parse decodes an existing output file into env lines so its values can be preserved
+	Parse(data []byte) ([]EnvLine, error)
+}
+
+// renderer returns the renderer for the configured format
+// The line format supplies the line endings, and for dotenv also the BOM and final newline.
+func (g *Generator) renderer(format lineFormat) (Renderer, error) {
+	switch g.config.Format {
+	case "", FormatDotenv:
+		return dotenvRenderer{format: format}, nil
+	case FormatJSON:
+		return jsonRenderer{separator: g.config.NestSeparator, eol: format.eol}, nil
+	case FormatYAML:
+		return yamlRenderer{separator: g.config.NestSeparator, comments: g.config.YAMLComments, eol: format.eol}, nil
+	case FormatK8sSecret, FormatK8sSplit:
+		return newK8sRenderer(g.config, g.config.Format == FormatK8sSplit, format.eol)
+	case FormatShell, FormatFish, FormatPowerShell:
+		return shellRenderer{dialect: shellDialects[g.config.Format], eol: format.eol}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format: %s", g.config.Format)
+	}
+}
+
+// dotenvRenderer writes env lines back exactly as they are
+type dotenvRenderer struct {
+	format lineFormat
+}
+
+// Render joins the raw lines using the line endings, BOM and final newline of the format
+func (r dotenvRenderer) Render(lines []EnvLine) ([]byte, error) {
+	rawLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		rawLines = append(rawLines, line.Raw)
+	}
+	return r.format.join(rawLines), nil
+}
+
+// Parse splits an env file into classified lines
+func (r dotenvRenderer) Parse(data []byte) ([]EnvLine, error) {
+	rawLines, _, err := readLinesWithFormat(bytes.NewReader(data), 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvLines(rawLines), nil
+}
+
+// withLineEndings converts the LF line endings of rendered content to eol
+// Only renderers that escape newlines inside values may use it.
+func withLineEndings(data []byte, eol string) []byte {
+	if eol == "" || eol == "\n" {
+		return data
+	}
+	return bytes.ReplaceAll(data, []byte("\n"), []byte(eol))
+}
+
+// valueNode is an ordered tree of keys used by the structured renderers
+// Leaves hold values; with a nest separator, keys sharing a prefix become children of one node.
+type valueNode struct {
+	key      string
+	value    string
+	children []*valueNode
+	comments []string // Comment lines shown before the key, where the format supports them
+	branch   bool
+}
+
+// child returns the child node with the given key, or nil
+func (n *valueNode) child(key string) *valueNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// buildValueTree arranges env lines into a tree, nesting keys by separator when one is set
+// Comment lines are attached to the key that follows them; trailing comments go on the root.
+// Nesting lower-cases every key, so DB__HOST and DEBUG become db.host and debug.
+func buildValueTree(lines []EnvLine, separator string) (*valueNode, error) {
+	root := &valueNode{branch: true}
+	var pending []string
+
+	for _, line := range lines {
+		if line.Type != LineTypeKeyValue {
+			if comment := strings.TrimSpace(line.Raw); comment != "" {
+				pending = append(pending, comment)
+			}
+			continue
+		}
+
+		path := []string{line.Key}
+		if separator != "" {
+			path = strings.Split(strings.ToLower(line.Key), strings.ToLower(separator))
+			for _, segment := range path {
+				if segment == "" {
+					return nil, fmt.Errorf("key %s has an empty segment when nested by %q", line.Key, separator)
+				}
+			}
+		}
+
+		// Comments go before the outermost node this key creates, so a section comment
+		// ends up above the nested mapping rather than inside it
+		node := root
+		var attach *valueNode
+		for i, segment := range path {
+			next := node.child(segment)
+			last := i == len(path)-1
+			switch {
+			case next == nil:
+				next = &valueNode{key: segment, branch: !last}
+				node.children = append(node.children, next)
+				if attach == nil {
+					attach = next
+				}
+			case last && !next.branch:
+				// Duplicate key; the later value wins
+			case last || !next.branch:
+				return nil, fmt.Errorf("key %s conflicts with another key when nested by %q", line.Key, separator)
+			}
+			node = next
+		}
+		node.value = unquoteValue(line.Value)
+		if attach == nil {
+			attach = node
+		}
+		attach.comments = append(attach.comments, pending...)
+		pending = nil
+	}
+
+	root.comments = pending
+	return root, nil
+}
+
+// flattenValueTree turns a tree back into env lines, joining nested keys with the separator
+// Nested keys are upper-cased, matching the usual shape of environment variable names.
+func flattenValueTree(root *valueNode, separator string) []EnvLine {
+	var lines []EnvLine
+	var walk func(node *valueNode, prefix string)
+	walk = func(node *valueNode, prefix string) {
+		for _, comment := range node.comments {
+			lines = append(lines, EnvLine{Type: LineTypeComment, Raw: comment})
+		}
+		if node != root {
+			key := node.key
+			if prefix != "" {
+				key = prefix + separator + key
+			}
+			if !node.branch {
+				if separator != "" {
+					key = strings.ToUpper(key)
+				}
+				lines = append(lines, parseEnvLine(key+"="+quoteValue(node.value)))
+				return
+			}
+			prefix = key
+		}
+		for _, child := range node.children {
+			walk(child, prefix)
+		}
+	}
+	walk(root, "")
+	return lines
+}
+
+// unquoteValue returns the value a dotenv loader would see for a raw value
+// Double-quoted values support backslash escapes, single-quoted values are literal,
+// and unquoted values end at an inline " #" comment.
+func unquoteValue(raw string) string {
+	value := strings.TrimSpace(raw)
+	if len(value) >= 2 {
+		switch value[0] {
+		case '"':
+			if end := closingQuote(value); end > 0 {
+				if unquoted, err := strconv.Unquote(value[:end+1]); err == nil {
+					return unquoted
+				}
+				return value[1:end]
+			}
+		case '\'':
+			if end := strings.IndexByte(value[1:], '\''); end >= 0 {
+				return value[1 : end+1]
+			}
+		}
+	}
+
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	return value
+}
+
+// closingQuote returns the index of the unescaped double quote closing value, or -1
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// quoteValue formats a value so unquoteValue returns it unchanged
+func quoteValue(value string) string {
+	if value == "" || !strings.ContainsAny(value, " \t\r\n\"'#\\") {
+		return value
+	}
+	return strconv.Quote(value)
+}
+
+// canonicalizeKeys maps keys read from a structured file onto the template's spelling
+// Nesting loses the original case of a key, so keys are matched case-insensitively.
+func canonicalizeKeys(lines []EnvLine, templateInfo map[string]TemplateInfo) {
+	byFold := make(map[string]string, len(templateInfo))
+	for key := range templateInfo {
+		byFold[strings.ToLower(key)] = key
+	}
+
+	for i, line := range lines {
+		if line.Type != LineTypeKeyValue {
+			continue
+		}
+		if _, ok := templateInfo[line.Key]; ok {
+			continue
+		}
+		if key, ok := byFold[strings.ToLower(line.Key)]; ok {
+			lines[i] = parseEnvLine(key + "=" + line.Value)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package generator
+
+import (
+	"testing"
+)
+
+// TestUnquoteValue tests how raw dotenv values are interpreted
+func TestUnquoteValue(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"plain", "plain"},
+		{" spaced ", "spaced"},
+		{`"double quoted"`, "double quoted"},
+		{`"escaped \"quote\" and\nnewline"`, "escaped \"quote\" and\nnewline"},
+		{`'single $literal'`, "single $literal"},
+		{"value # comment", "value"},
+		{"value#not-a-comment", "value#not-a-comment"},
+		{`"quoted" # comment`, "quoted"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := unquoteValue(tt.raw); got != tt.want {
+			t.Errorf("unquoteValue(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+// TestQuoteValueRoundTrip tests that quoted values unquote to themselves
+func TestQuoteValueRoundTrip(t *testing.T) {
+	values := []string{"", "abc", "with space", `a"b`, "it's", "a # b", "back\\slash", "multi\nline", " lead"}
+	for _, value := range values {
+		if got := unquoteValue(quoteValue(value)); got != value {
+			t.Errorf("Round trip of %q gave %q (quoted as %s)", value, got, quoteValue(value))
+		}
+	}
+}
+
+// TestBuildValueTreeNesting tests nesting keys by separator and detecting conflicts
+func TestBuildValueTreeNesting(t *testing.T) {
+	lines := []EnvLine{
+		parseEnvLine("# Database"),
+		parseEnvLine("DB__HOST=localhost"),
+		parseEnvLine("DB__PORT=5432"),
+		parseEnvLine("APP_NAME=demo"),
+	}
+
+	root, err := buildValueTree(lines, "__")
+	if err != nil {
+		t.Fatalf("Failed to build tree: %v", err)
+	}
+
+	db := root.child("db")
+	if db == nil || !db.branch || len(db.children) != 2 {
+		t.Fatalf("Expected db to nest host and port, got %+v", db)
+	}
+	if db.child("host").value != "localhost" {
+		t.Errorf("Unexpected db.host value: %q", db.child("host").value)
+	}
+	if len(db.comments) != 1 {
+		t.Errorf("Comment should be attached to the db mapping, got %q", db.comments)
+	}
+
+	// Flattening restores the env keys
+	flat := flattenValueTree(root, "__")
+	var keys []string
+	for _, line := range flat {
+		if line.Type == LineTypeKeyValue {
+			keys = append(keys, line.Key)
+		}
+	}
+	if len(keys) != 3 || keys[0] != "DB__HOST" || keys[2] != "APP_NAME" {
+		t.Errorf("Unexpected flattened keys: %v", keys)
+	}
+
+	// A key can't be both a value and a parent
+	conflicting := append(lines, parseEnvLine("DB=postgres"))
+	if _, err := buildValueTree(conflicting, "__"); err == nil {
+		t.Error("Expected a conflict between DB and DB__HOST")
+	}
+}
@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Directives that repeat a block of template lines
+const (
+	// repeatDirective starts a block repeated for a range, e.g. # @repeat i in 1..${WORKERS}
+	repeatDirective = "repeat"
+	// endRepeatDirective ends a repeated block
+	endRepeatDirective = "endrepeat"
+)
+
+// maxRepeatCount limits the instances of a block so a typo in a bound can't flood the output
+const maxRepeatCount = 1000
+
+var (
+	// repeatRe matches the argument of @repeat and captures the variable and both bounds
+	repeatRe = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s+in\s+(\S+?)\.\.(\S+)$`)
+	// repeatBoundKeyRe matches a bound read from a key, e.g. ${WORKERS}
+	repeatBoundKeyRe = regexp.MustCompile(`^\$\{([A-Za-z_][A-Za-z0-9_]*)\}$`)
+)
+
+// repetition is a parsed @repeat directive
+type repetition struct {
+	variable   string
+	start, end int             // Inclusive; no instances when end is below start
+	nested     map[string]bool // Variables of the @repeat blocks inside the block
+}
+
+// parseRepetition parses the argument of a @repeat directive
+func (g *Generator) parseRepetition(arg string, defined keyLookup) (repetition, error) {
+	match := repeatRe.FindStringSubmatch(arg)
+	if match == nil {
+		return repetition{}, fmt.Errorf("invalid @%s %q; use @%s i in 1..3 or @%s i in 1..${KEY}", repeatDirective, arg, repeatDirective, repeatDirective)
+	}
+	r := repetition{variable: match[1]}
+	var err error
+	if r.start, err = g.repeatBound(match[2], defined); err != nil {
+		return repetition{}, err
+	}
+	if r.end, err = g.repeatBound(match[3], defined); err != nil {
+		return repetition{}, err
+	}
+	if count := r.end - r.start + 1; count > maxRepeatCount {
+		return repetition{}, fmt.Errorf("@%s %s makes %d instances; at most %d are allowed", repeatDirective, arg, count, maxRepeatCount)
+	}
+	return r, nil
+}
+
+// repeatBound returns the value of a range bound, which is a number or ${KEY} for the value
+// of a key defined above the block
+func (g *Generator) repeatBound(bound string, defined keyLookup) (int, error) {
+	text := bound
+	if match := repeatBoundKeyRe.FindStringSubmatch(bound); match != nil {
+		value, ok := g.keyValue(match[1], defined)
+		if !ok {
+			return 0, fmt.Errorf("@%s bound %s refers to %s, which is not defined above it", repeatDirective, bound, match[1])
+		}
+		text = strings.TrimSpace(value)
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 {
+		if text != bound {
+			return 0, fmt.Errorf("@%s bound %s is %q, not a whole number", repeatDirective, bound, text)
+		}
+		return 0, fmt.Errorf("@%s bound %q is not a whole number or ${KEY}", repeatDirective, bound)
+	}
+	return n, nil
+}
+
+// takeRepeatBlock splits the lines following a @repeat directive into the lines of its block
+// and the lines after its @endrepeat
+func takeRepeatBlock(queue templateLayer, source lineSource) (block, rest templateLayer, err error) {
+	depth := 0
+	for i, line := range queue.lines {
+		switch name, _, _ := parseAnnotation(line); name {
+		case repeatDirective:
+			depth++
+		case endRepeatDirective:
+			if depth == 0 {
+				block = templateLayer{lines: queue.lines[:i], sources: queue.sources[:i]}
+				rest = templateLayer{lines: queue.lines[i+1:], sources: queue.sources[i+1:]}
+				return block, rest, nil
+			}
+			depth--
+		}
+	}
+	return templateLayer{}, templateLayer{}, fmt.Errorf("%s: @%s is never closed with @%s", source, repeatDirective, endRepeatDirective)
+}
+
+// expand returns the lines of every instance of a repeated block, in order
+// Each key must use the variable in its name so instances don't define the same key, and
+// placeholders can't write to a file=, which every instance would overwrite.
+func (r repetition) expand(block templateLayer) (templateLayer, error) {
+	reference := "${" + r.variable + "}"
+	r.nested = make(map[string]bool)
+	for i, line := range block.lines {
+		if isCommentOrEmpty(line) {
+			if name, arg, _ := parseAnnotation(line); name == repeatDirective {
+				variable, _, _ := strings.Cut(arg, " ")
+				if variable == r.variable {
+					return templateLayer{}, fmt.Errorf("%s: nested @%s reuses the variable %s", block.sources[i], repeatDirective, r.variable)
+				}
+				r.nested[variable] = true
+			}
+			continue
+		}
+		key, value, ok := parseKeyValue(line)
+		if !ok {
+			continue
+		}
+		if !strings.Contains(key, reference) {
+			return templateLayer{}, fmt.Errorf("%s: %s is repeated by @%s but doesn't use %s, so every instance would define the same key", block.sources[i], key, repeatDirective, reference)
+		}
+		// Malformed placeholders are reported when the instances are generated
+		placeholders, _ := placeholdersIn(value)
+		for _, p := range placeholders {
+			if p.file != "" && !p.shared {
+				return templateLayer{}, fmt.Errorf("%s: ${%s} of %s writes to %s, which every instance of @%s would overwrite; move the key out of the block or use a shared placeholder", block.sources[i], p.name, key, p.file, repeatDirective)
+			}
+		}
+	}
+
+	var expanded templateLayer
+	for index := r.start; index <= r.end; index++ {
+		// Set instances of several lines apart so each keeps its own comment group
+		if index > r.start && len(block.lines) > 1 &&
+			strings.TrimSpace(block.lines[0]) != "" && strings.TrimSpace(block.lines[len(block.lines)-1]) != "" {
+			expanded.append("", lineSource{path: block.sources[0].path})
+		}
+		for i, line := range block.lines {
+			expanded.append(r.instanceLine(line, index), block.sources[i])
+		}
+	}
+	return expanded, nil
+}
+
+// instanceLine returns a line of a repeated block for one instance
+// The variable is replaced by the index, and the other placeholders of key lines get a name
+// of their own per instance, e.g. ${token} becomes ${token[2]}, so each instance gets its
+// own values. Variables of nested blocks are left for those blocks, and shared placeholders
+// keep their value across instances.
+func (r repetition) instanceLine(line string, index int) string {
+	indexText := strconv.Itoa(index)
+	comment := isCommentOrEmpty(line)
+
+	var result strings.Builder
+	last := 0
+	for _, match := range placeholderRe.FindAllStringSubmatchIndex(line, -1) {
+		if match[0] > 0 && line[match[0]-1] == '\\' {
+			continue
+		}
+		contents := line[match[2]:match[3]]
+		name, options, hasOptions := strings.Cut(contents, ":")
+		switch {
+		case strings.TrimSpace(name) == r.variable && !hasOptions:
+			result.WriteString(line[last:match[0]])
+			result.WriteString(indexText)
+		case r.nested[strings.TrimSpace(name)] && !hasOptions, strings.TrimSpace(name) == sharedNamespace && hasOptions:
+			continue
+		case !comment:
+			result.WriteString(line[last:match[2]])
+			result.WriteString(strings.TrimSpace(name) + "[" + indexText + "]")
+			if hasOptions {
+				result.WriteString(":" + options)
+			}
+			result.WriteString("}")
+		default:
+			continue
+		}
+		last = match[1]
+	}
+	result.WriteString(line[last:])
+	return result.String()
+}
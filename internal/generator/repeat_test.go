@@ -0,0 +1,158 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRepeatInstanceLine tests substituting the variable and namespacing placeholders
+func TestRepeatInstanceLine(t *testing.T) {
+	r := repetition{variable: "i", nested: map[string]bool{"j": true}}
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{"WORKER_${i}_TOKEN=${token}", "WORKER_2_TOKEN=${token[2]}"},
+		{"WORKER_${i}_URL=http://worker-${i}:80", "WORKER_2_URL=http://worker-2:80"},
+		{"WORKER_${i}_KEY=${key:length=8,charset=numeric}", "WORKER_2_KEY=${key[2]:length=8,charset=numeric}"},
+		{`WORKER_${i}_RAW=\${token}`, `WORKER_2_RAW=\${token}`},
+		{"SHARD_${i}_${j}=${seed}", "SHARD_2_${j}=${seed[2]}"},
+		{"# Token of worker ${i}, not ${token}", "# Token of worker 2, not ${token}"},
+	}
+
+	for _, test := range tests {
+		if result := r.instanceLine(test.line, 2); result != test.expected {
+			t.Errorf("instanceLine(%q) = %q, expected %q", test.line, result, test.expected)
+		}
+	}
+}
+
+// TestRepeatBlocks tests expanding @repeat blocks and growing them on re-runs
+func TestRepeatBlocks(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	template := strings.Join([]string{
+		"WORKERS=2",
+		"",
+		"# @repeat i in 1..${WORKERS}",
+		"# Token of worker ${i}",
+		"WORKER_${i}_TOKEN=${token}",
+		"# @endrepeat",
+		"",
+		"# @repeat s in 0..1",
+		"SHARD_${s}_SEED=${seed:length=6,charset=numeric}",
+		"# @endrepeat",
+		"",
+	}, "\n")
+	templatePath := filepath.Join(tempDir, ".env.example")
+	outputPath := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(templatePath, []byte(template), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	config := Config{TemplatePath: templatePath, OutputPath: outputPath}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	data, _ := os.ReadFile(outputPath)
+	env := readEnv(t, outputPath)
+	for _, key := range []string{"WORKER_1_TOKEN", "WORKER_2_TOKEN", "SHARD_0_SEED", "SHARD_1_SEED"} {
+		if env[key] == "" {
+			t.Fatalf("Expected %s in output:\n%s", key, data)
+		}
+	}
+	if _, ok := env["WORKER_3_TOKEN"]; ok {
+		t.Errorf("Expected two workers:\n%s", data)
+	}
+	if env["WORKER_1_TOKEN"] == env["WORKER_2_TOKEN"] {
+		t.Errorf("Each instance should get its own token, got %q twice", env["WORKER_1_TOKEN"])
+	}
+	if len(env["SHARD_0_SEED"]) != 6 {
+		t.Errorf("Expected placeholder options to apply to instances, got %q", env["SHARD_0_SEED"])
+	}
+	if !strings.Contains(string(data), "# Token of worker 1\nWORKER_1_TOKEN=") || !strings.Contains(string(data), "\n\n# Token of worker 2\nWORKER_2_TOKEN=") {
+		t.Errorf("Expected each instance with its own comment group:\n%s", data)
+	}
+
+	// Raising the count in .env adds the new instance and keeps the existing ones
+	os.WriteFile(outputPath, []byte(strings.Replace(string(data), "WORKERS=2", "WORKERS=3", 1)), 0600)
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate .env file: %v", err)
+	}
+	updated := readEnv(t, outputPath)
+	if updated["WORKER_1_TOKEN"] != env["WORKER_1_TOKEN"] || updated["WORKER_2_TOKEN"] != env["WORKER_2_TOKEN"] {
+		t.Errorf("Existing instances should be kept, got %v", updated)
+	}
+	if updated["WORKER_3_TOKEN"] == "" {
+		t.Errorf("Expected a third worker to be added, got %v", updated)
+	}
+}
+
+// TestRepeatNested tests nested @repeat blocks
+func TestRepeatNested(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	template := "# @repeat i in 1..2\n# @repeat j in 1..${i}\nSHARD_${i}_${j}=${key}\n# @endrepeat\n# @endrepeat\n"
+	templatePath := filepath.Join(tempDir, ".env.example")
+	outputPath := filepath.Join(tempDir, ".env")
+	os.WriteFile(templatePath, []byte(template), 0644)
+
+	if err := New(Config{TemplatePath: templatePath, OutputPath: outputPath}).Generate(); err != nil {
+		t.Fatalf("Failed to generate .env file: %v", err)
+	}
+	env := readEnv(t, outputPath)
+	if len(env) != 3 || env["SHARD_1_1"] == "" || env["SHARD_2_1"] == "" || env["SHARD_2_2"] == "" {
+		t.Errorf("Expected SHARD_1_1, SHARD_2_1 and SHARD_2_2, got %v", env)
+	}
+	if env["SHARD_2_1"] == env["SHARD_2_2"] {
+		t.Errorf("Nested instances should get their own values, got %v", env)
+	}
+}
+
+// TestRepeatErrors tests line-numbered errors for malformed @repeat blocks
+func TestRepeatErrors(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{"unclosed", "# @repeat i in 1..2\nK_${i}=1\n", ".env.example:1: @repeat is never closed with @endrepeat"},
+		{"stray end", "A=1\n# @endrepeat\n", ".env.example:2: @endrepeat without a matching @repeat"},
+		{"syntax", "# @repeat i from 1 to 2\n# @endrepeat\n", ".env.example:1: invalid @repeat"},
+		{"undefined bound", "# @repeat i in 1..${WORKERS}\n# @endrepeat\n", ".env.example:1: @repeat bound ${WORKERS} refers to WORKERS"},
+		{"bound not a number", "WORKERS=many\n# @repeat i in 1..${WORKERS}\n# @endrepeat\n", `.env.example:2: @repeat bound ${WORKERS} is "many"`},
+		{"too many", "# @repeat i in 1..100000\n# @endrepeat\n", ".env.example:1: @repeat i in 1..100000 makes 100000 instances"},
+		{"key without variable", "# @repeat i in 1..2\nTOKEN=${token}\n# @endrepeat\n", ".env.example:2: TOKEN is repeated by @repeat but doesn't use ${i}"},
+		{"secret file", "# @repeat i in 1..2\nTOKEN_${i}=${token:file=./secrets/token}\n# @endrepeat\n", ".env.example:2: ${token} of TOKEN_${i} writes to ./secrets/token, which every instance of @repeat would overwrite"},
+		{"reused variable", "# @repeat i in 1..2\n# @repeat i in 1..2\n# @endrepeat\n# @endrepeat\n", ".env.example:2: nested @repeat reuses the variable i"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			templatePath := filepath.Join(tempDir, ".env.example")
+			if err := os.WriteFile(templatePath, []byte(test.template), 0644); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+			err := New(Config{TemplatePath: templatePath, OutputPath: filepath.Join(tempDir, ".env")}).Generate()
+			if err == nil || !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected error containing %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package generator
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultTemplateFileMode is the permission of templates written by InitTemplate,
+// which are meant to be committed and shared
+const DefaultTemplateFileMode os.FileMode = 0644
+
+var (
+	// secretKeyRe matches key names that conventionally hold secrets, such as API_KEY or
+	// DB_PASSWORD, but not names of their locations such as TOKEN_URL or SSH_KEY_PATH
+	secretKeyRe = regexp.MustCompile(`(?i)(^|_)(SECRET|PASSWD|PWD|TOKEN|KEY|SALT)$|PASSWORD`)
+	// tokenValueRe matches values shaped like generated tokens: no spaces, separators or schemes
+	tokenValueRe = regexp.MustCompile(`^[A-Za-z0-9+/=_\-.]+$`)
+)
+
+const (
+	// minSecretLength is the shortest value treated as a secret based on its shape alone
+	minSecretLength = 16
+	// minSecretEntropy is the Shannon entropy per character above which a long value looks random
+	minSecretEntropy = 3.5
+)
+
+// InitTemplate writes a template to Config.TemplatePath from an existing env file
+// Values that look like secrets become placeholders whose options reproduce their shape;
+// everything else, including comments and blank lines, is kept as written.
+func (g *Generator) InitTemplate(envPath string) error {
+	lines, format, err := g.readEnvFileWithStructure(envPath)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	var templateLines []string
+	for _, line := range lines {
+		if line.Type != LineTypeKeyValue {
+			// The derivation context only describes generated files
+			if !isDeriveHeader(line.Raw) {
+				templateLines = append(templateLines, line.Raw)
+			}
+			continue
+		}
+		templateLines = append(templateLines, templateLine(line))
+	}
+
+	mode := g.config.FileMode
+	if mode == 0 {
+		mode = DefaultTemplateFileMode
+	}
+	if err := writeFileAtomic(g.config.TemplatePath, format.withEOL(g.config.EOL).join(templateLines), mode); err != nil {
+		return fmt.Errorf("failed to write template file: %w", err)
+	}
+
+	return nil
+}
+
+// templateLine turns a key=value line of an env file into its template form
+func templateLine(line EnvLine) string {
+	prefix := line.Raw[:strings.Index(line.Raw, "=")+1]
+	value := unquoteValue(line.Value)
+
+	if !looksSecret(line.Key, value) {
+		// Literal ${ would otherwise be read as a placeholder
+		literal := strings.ReplaceAll(line.Value, "${", `\${`)
+
+		// A password in a URL would otherwise be committed with the template
+		if password, ok := urlPassword(value); ok && !strings.Contains(value, "${") {
+			literal = strings.Replace(literal, ":"+password+"@", ":"+placeholderFor(line.Key+"_PASSWORD", password)+"@", 1)
+		}
+		return prefix + literal
+	}
+
+	// Swap the value for the placeholder in place, keeping quotes and inline comments,
+	// unless escapes mean the value isn't spelled out literally
+	if !strings.Contains(line.Value, value) {
+		return prefix + placeholderFor(line.Key, value)
+	}
+	return prefix + strings.Replace(line.Value, value, placeholderFor(line.Key, value), 1)
+}
+
+// looksSecret guesses whether a value is a secret from its key name, shape and entropy
+// A secret key name is not enough on its own: URLs, paths and text with spaces are config.
+func looksSecret(key, value string) bool {
+	if value == "" || strings.Contains(value, "${") || looksLikeLocation(value) {
+		return false
+	}
+	if secretKeyRe.MatchString(key) && !strings.ContainsAny(value, " \t") {
+		return true
+	}
+	return len(value) >= minSecretLength && tokenValueRe.MatchString(value) && shannonEntropy(value) >= minSecretEntropy
+}
+
+// looksLikeLocation reports whether a value is a URL or a file path rather than a credential
+// Relative paths are told from base64 by the extension of their last element.
+func looksLikeLocation(value string) bool {
+	if strings.Contains(value, "://") {
+		return true
+	}
+	for _, prefix := range []string{"/", "./", "../", "~/"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(value, "/") && path.Ext(value) != ""
+}
+
+// urlPassword returns the password of the userinfo of a URL, as written in value
+func urlPassword(value string) (string, bool) {
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return "", false
+	}
+	if password, ok := u.User.Password(); !ok || password == "" {
+		return "", false
+	}
+
+	// The parsed password is unescaped, so it is cut out of the authority as written
+	_, rest, _ := strings.Cut(value, "://")
+	if end := strings.IndexAny(rest, "/?#"); end >= 0 {
+		rest = rest[:end]
+	}
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		return "", false
+	}
+	_, password, _ := strings.Cut(rest[:at], ":")
+	return password, password != ""
+}
+
+// shannonEntropy returns the entropy of value in bits per character
+func shannonEntropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, c := range value {
+		counts[c]++
+		total++
+	}
+
+	entropy := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// placeholderFor builds a placeholder named after the key whose options regenerate
+// values of the same length and character set, leaving out options that match the defaults
+func placeholderFor(key, value string) string {
+	var options []string
+	if length := len(value); length != DefaultValueLength {
+		options = append(options, "length="+strconv.Itoa(length))
+	}
+	if charset := charsetOf(value); charset != CharsetAlphanumeric {
+		options = append(options, "charset="+string(charset))
+	}
+
+	name := strings.ToLower(key)
+	if len(options) == 0 {
+		return "${" + name + "}"
+	}
+	return "${" + name + ":" + strings.Join(options, ",") + "}"
+}
+
+// charsetOf returns the smallest character set that covers every character of value
+// Values with characters outside every set fall back to alphanumeric.
+func charsetOf(value string) CharsetType {
+	var lower, upper, digit bool
+	for _, c := range value {
+		switch {
+		case 'a' <= c && c <= 'z':
+			lower = true
+		case 'A' <= c && c <= 'Z':
+			upper = true
+		case '0' <= c && c <= '9':
+			digit = true
+		default:
+			return CharsetAlphanumeric
+		}
+	}
+
+	switch {
+	case digit && !lower && !upper:
+		return CharsetNumeric
+	case digit:
+		return CharsetAlphanumeric
+	case lower && upper:
+		return CharsetAlphabetic
+	case upper:
+		return CharsetUppercase
+	default:
+		return CharsetLowercase
+	}
+}
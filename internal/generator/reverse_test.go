@@ -0,0 +1,97 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTemplateLine tests which values become placeholders and how their options are inferred
+func TestTemplateLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"DB_HOST=localhost", "DB_HOST=localhost"},
+		{"DEBUG=true", "DEBUG=true"},
+		{"APP_SECRET=abcdefghijklmnopqrstuvwx", "APP_SECRET=${app_secret:charset=lowercase}"},
+		{`STRIPE_KEY="sk_live_0123456789"`, `STRIPE_KEY="${stripe_key:length=18}"`},
+		{"ADMIN_PASSWORD_HASH=hunter", "ADMIN_PASSWORD_HASH=${admin_password_hash:length=6,charset=lowercase}"},
+		{"PIN_TOKEN=123456 # six digits", "PIN_TOKEN=${pin_token:length=6,charset=numeric} # six digits"},
+		{"SESSION=Zx81Kq0Lw9Pm3Rt7Yb2Nc6Vd", "SESSION=${session}"},
+		{"GREETING=hello world and everyone", "GREETING=hello world and everyone"},
+		{"MONKEY=banana", "MONKEY=banana"},
+		{"API_KEY=", "API_KEY="},
+		{"LITERAL=cost ${price}", `LITERAL=cost \${price}`},
+		{"DATABASE_URL=postgres://user:hunter2@db/app", "DATABASE_URL=postgres://user:${database_url_password:length=7}@db/app"},
+		{`REDIS_URL="redis://:p%40ss@cache:6379/0" # cache`, `REDIS_URL="redis://:${redis_url_password:length=6}@cache:6379/0" # cache`},
+		{"PUBLIC_URL=https://user@example.com/path", "PUBLIC_URL=https://user@example.com/path"},
+		{"TOKEN_URL=https://example.com/token", "TOKEN_URL=https://example.com/token"},
+		{"AUTH_TOKEN=https://example.com/token", "AUTH_TOKEN=https://example.com/token"},
+		{"SSH_KEY_PATH=~/.ssh/id_ed25519", "SSH_KEY_PATH=~/.ssh/id_ed25519"},
+		{"KEY_FILE=./secrets/key.pem", "KEY_FILE=./secrets/key.pem"},
+		{"PWD_DIR=/var/lib/app", "PWD_DIR=/var/lib/app"},
+		{"TLS_KEY=certs/server.key", "TLS_KEY=certs/server.key"},
+		{"JWT_SECRET=/run/secrets/jwt", "JWT_SECRET=/run/secrets/jwt"},
+		{"SIGNING_KEY=ab/cd+ef", "SIGNING_KEY=${signing_key:length=8}"},
+	}
+
+	for _, tt := range tests {
+		if got := templateLine(parseEnvLine(tt.line)); got != tt.want {
+			t.Errorf("templateLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+// TestInitTemplate tests writing a template that keeps comments, grouping and line format
+func TestInitTemplate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	envPath := filepath.Join(tempDir, ".env")
+	envContent := "# genenv:derive namespace=default version=v1\r\n# Database\r\nDB_HOST=localhost\r\nDB_PASSWORD=Zx81Kq0Lw9Pm3Rt7Yb2Nc6Vd\r\n\r\n# Misc\r\nDEBUG=true\r\n"
+	if err := os.WriteFile(envPath, []byte(envContent), 0600); err != nil {
+		t.Fatalf("Failed to write env file: %v", err)
+	}
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := New(Config{TemplatePath: templatePath}).InitTemplate(envPath); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	content, err := os.ReadFile(templatePath)
+	if err != nil {
+		t.Fatalf("Failed to read template: %v", err)
+	}
+	expected := "# Database\r\nDB_HOST=localhost\r\nDB_PASSWORD=${db_password}\r\n\r\n# Misc\r\nDEBUG=true\r\n"
+	if string(content) != expected {
+		t.Errorf("Unexpected template:\n%q\nwant\n%q", content, expected)
+	}
+
+	info, err := os.Stat(templatePath)
+	if err != nil {
+		t.Fatalf("Failed to stat template: %v", err)
+	}
+	if info.Mode().Perm() != DefaultTemplateFileMode {
+		t.Errorf("Expected template mode %o, got %o", DefaultTemplateFileMode, info.Mode().Perm())
+	}
+}
+
+// TestGeneratorPlaceholderLengthAndCharset tests the length and charset placeholder options
+func TestGeneratorPlaceholderLengthAndCharset(t *testing.T) {
+	gen := New(Config{})
+	value, err := gen.generateValueFromTemplate("${pin:length=6,charset=numeric}", map[string]string{})
+	if err != nil {
+		t.Fatalf("Failed to generate value: %v", err)
+	}
+	if len(value) != 6 || charsetOf(value) != CharsetNumeric {
+		t.Errorf("Expected a 6 digit value, got %q", value)
+	}
+
+	if _, err := gen.generateValueFromTemplate("${pin:charset=emoji}", map[string]string{}); err == nil {
+		t.Error("Expected an error for an unknown charset")
+	}
+}
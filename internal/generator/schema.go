@@ -0,0 +1,380 @@
+package generator
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldType is the value type declared by a @type annotation
+type FieldType string
+
+const (
+	// TypeString accepts any value; it is the type of keys without @type
+	TypeString FieldType = "string"
+	// TypeInt accepts base 10 integers
+	TypeInt FieldType = "int"
+	// TypeFloat accepts decimal numbers
+	TypeFloat FieldType = "float"
+	// TypeBool accepts true/false, 1/0 and the other spellings of strconv.ParseBool
+	TypeBool FieldType = "bool"
+	// TypeURL accepts absolute URLs with a scheme and host
+	TypeURL FieldType = "url"
+	// TypeEmail accepts a bare e-mail address
+	TypeEmail FieldType = "email"
+	// TypePort accepts TCP/UDP port numbers from 1 to 65535
+	TypePort FieldType = "port"
+	// TypeDuration accepts Go durations such as 30s or 1h30m
+	TypeDuration FieldType = "duration"
+)
+
+// annotationPrefix starts a schema annotation in a template comment
+const annotationPrefix = "@"
+
+// Annotation is a single @name argument line found in a key's comment group
+type Annotation struct {
+	Name string
+	Arg  string
+	Line int // 1-based template line of the annotation
+}
+
+// Field is the schema of a template key, built from the annotations in its comment group
+type Field struct {
+	Key      string
+	Line     int // 1-based template line of the key
+	Type     FieldType
+	Required bool
+	Enum     []string
+	Pattern  *regexp.Regexp
+	// Shared keys get the same generated values in every profile
+	Shared bool
+
+	// Comments holds the text of the key's comment group without the annotations
+	Comments []string
+	// Default is the literal value of the key in the template; empty for generated keys
+	Default string
+	// Generated tells whether the value comes from a placeholder
+	Generated bool
+
+	annotations map[string]Annotation
+}
+
+// Schema holds the fields of a template in the order their keys appear
+type Schema struct {
+	TemplatePath string
+	Fields       []*Field
+
+	byKey map[string]*Field
+	// sources locates the template lines when they were merged from several files
+	sources []lineSource
+}
+
+// locate returns the file and line a 1-based template line was read from
+func (s *Schema) locate(line int) (string, int) {
+	if line > 0 && line <= len(s.sources) {
+		return s.sources[line-1].path, s.sources[line-1].line
+	}
+	return s.TemplatePath, line
+}
+
+// Field returns the schema of a key, or nil if the template doesn't define it
+func (s *Schema) Field(key string) *Field {
+	return s.byKey[key]
+}
+
+// parseAnnotation parses a comment line such as "# @enum debug|info|warn"
+func parseAnnotation(line string) (name, arg string, ok bool) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "#") {
+		return "", "", false
+	}
+	trimmed = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+	if !strings.HasPrefix(trimmed, annotationPrefix) {
+		return "", "", false
+	}
+	name, arg, _ = strings.Cut(strings.TrimPrefix(trimmed, annotationPrefix), " ")
+	return name, strings.TrimSpace(arg), name != ""
+}
+
+// commentText returns the text of a comment line without the leading #
+func commentText(line string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "#"))
+}
+
+// parseSchema builds the schema of template lines from their comment group annotations
+// Annotations other than @type, @required, @enum and @pattern are left for other features.
+// When a key is defined twice, the last definition wins, as in parseTemplateInfo.
+func parseSchema(templatePath string, lines []string) (*Schema, error) {
+	schema := &Schema{TemplatePath: templatePath, byKey: make(map[string]*Field)}
+
+	for i, line := range lines {
+		if isCommentOrEmpty(line) {
+			continue
+		}
+		key, value, ok := parseKeyValue(line)
+		if !ok {
+			continue
+		}
+
+		field := &Field{Key: key, Line: i + 1, Type: TypeString, annotations: make(map[string]Annotation)}
+		if placeholders, err := placeholdersIn(value); err == nil && len(placeholders) > 0 {
+			field.Generated = true
+		} else {
+			field.Default = strings.ReplaceAll(unquoteValue(value), `\${`, "${")
+		}
+
+		for _, index := range commentGroupIndices(lines, i) {
+			name, arg, ok := parseAnnotation(lines[index])
+			if !ok {
+				if comment := commentText(lines[index]); comment != "" {
+					field.Comments = append(field.Comments, comment)
+				}
+				continue
+			}
+			if err := field.annotate(Annotation{Name: name, Arg: arg, Line: index + 1}); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", templatePath, index+1, err)
+			}
+		}
+
+		if previous, ok := schema.byKey[key]; ok {
+			*previous = *field
+			continue
+		}
+		schema.byKey[key] = field
+		schema.Fields = append(schema.Fields, field)
+	}
+
+	return schema, nil
+}
+
+// annotate applies a schema annotation to the field
+func (f *Field) annotate(a Annotation) error {
+	switch a.Name {
+	case "type":
+		switch t := FieldType(a.Arg); t {
+		case TypeString, TypeInt, TypeFloat, TypeBool, TypeURL, TypeEmail, TypePort, TypeDuration:
+			f.Type = t
+		default:
+			return fmt.Errorf("unknown @type %q; use string, int, float, bool, url, email, port or duration", a.Arg)
+		}
+	case "required":
+		f.Required = true
+	case sharedAnnotation:
+		f.Shared = true
+	case "enum":
+		if a.Arg == "" {
+			return fmt.Errorf("@enum needs values separated by |")
+		}
+		f.Enum = strings.Split(a.Arg, "|")
+		for i := range f.Enum {
+			f.Enum[i] = strings.TrimSpace(f.Enum[i])
+		}
+	case "pattern":
+		pattern, err := regexp.Compile(a.Arg)
+		if err != nil {
+			return fmt.Errorf("invalid @pattern: %w", err)
+		}
+		f.Pattern = pattern
+	default:
+		return nil
+	}
+
+	f.annotations[a.Name] = a
+	return nil
+}
+
+// Violation is a value in an env file that breaks an annotation of the template
+type Violation struct {
+	Key     string
+	Message string
+
+	// File and Line locate the offending value; Line is zero when the key is missing
+	// or the file isn't a line-based env file
+	File string
+	Line int
+
+	// Annotation is the template annotation the value breaks
+	TemplateFile string
+	Annotation   Annotation
+}
+
+// String formats the violation with both the env file and the template annotation location
+func (v Violation) String() string {
+	location := v.File
+	if v.Line > 0 {
+		location = fmt.Sprintf("%s:%d", v.File, v.Line)
+	}
+	return fmt.Sprintf("%s: %s %s (%s:%d: @%s)", location, v.Key, v.Message,
+		v.TemplateFile, v.Annotation.Line, strings.TrimSpace(v.Annotation.Name+" "+v.Annotation.Arg))
+}
+
+// ValidationError is returned when generated values break the template schema
+type ValidationError struct {
+	Violations []Violation
+}
+
+// Error lists every violation on its own line
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, "  "+v.String())
+	}
+	return fmt.Sprintf("%d value(s) do not match the template schema:\n%s", len(e.Violations), strings.Join(messages, "\n"))
+}
+
+// Validate checks env lines against the schema
+// withLines reports line numbers for each value, which only makes sense for dotenv files.
+func (s *Schema) Validate(envPath string, lines []EnvLine, withLines bool) []Violation {
+	var violations []Violation
+	found := make(map[string]bool)
+
+	for i, line := range lines {
+		if line.Type != LineTypeKeyValue {
+			continue
+		}
+		field := s.byKey[line.Key]
+		if field == nil {
+			continue
+		}
+		found[line.Key] = true
+
+		lineNumber := 0
+		if withLines {
+			lineNumber = i + 1
+		}
+		for _, violation := range field.check(unquoteValue(line.Value)) {
+			violation.File, violation.Line = envPath, lineNumber
+			violation.TemplateFile, violation.Annotation.Line = s.locate(violation.Annotation.Line)
+			violations = append(violations, violation)
+		}
+	}
+
+	for _, field := range s.Fields {
+		if field.Required && !found[field.Key] {
+			violation := Violation{
+				Key:        field.Key,
+				Message:    "is required but missing",
+				File:       envPath,
+				Annotation: field.annotations["required"],
+			}
+			violation.TemplateFile, violation.Annotation.Line = s.locate(violation.Annotation.Line)
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+// check returns the annotations a value breaks
+// Empty values count as unset, so only @required applies to them. Messages never quote the
+// value, which may be a secret.
+func (f *Field) check(value string) []Violation {
+	if value == "" {
+		if f.Required {
+			return []Violation{{Key: f.Key, Message: "is required but empty", Annotation: f.annotations["required"]}}
+		}
+		return nil
+	}
+
+	var violations []Violation
+	if message := checkType(f.Type, value); message != "" {
+		violations = append(violations, Violation{Key: f.Key, Message: message, Annotation: f.annotations["type"]})
+	}
+	if f.Enum != nil && !slices.Contains(f.Enum, value) {
+		violations = append(violations, Violation{
+			Key:        f.Key,
+			Message:    "must be one of " + strings.Join(f.Enum, "|"),
+			Annotation: f.annotations["enum"],
+		})
+	}
+	if f.Pattern != nil && !f.Pattern.MatchString(value) {
+		violations = append(violations, Violation{
+			Key:        f.Key,
+			Message:    "must match " + f.Pattern.String(),
+			Annotation: f.annotations["pattern"],
+		})
+	}
+	return violations
+}
+
+// checkType returns why value is not of the given type, or an empty string
+func checkType(fieldType FieldType, value string) string {
+	var ok bool
+	switch fieldType {
+	case TypeInt:
+		_, err := strconv.ParseInt(value, 10, 64)
+		ok = err == nil
+	case TypeFloat:
+		_, err := strconv.ParseFloat(value, 64)
+		ok = err == nil
+	case TypeBool:
+		_, err := strconv.ParseBool(value)
+		ok = err == nil
+	case TypeURL:
+		u, err := url.Parse(value)
+		ok = err == nil && u.Scheme != "" && u.Host != ""
+	case TypeEmail:
+		address, err := mail.ParseAddress(value)
+		ok = err == nil && address.Address == value
+	case TypePort:
+		port, err := strconv.Atoi(value)
+		ok = err == nil && port >= 1 && port <= 65535
+	case TypeDuration:
+		_, err := time.ParseDuration(value)
+		ok = err == nil
+	default:
+		ok = true
+	}
+
+	if ok {
+		return ""
+	}
+	return "must be of type " + string(fieldType)
+}
+
+// parseTemplateSchema parses the schema of the lines returned by readTemplateFile
+func (g *Generator) parseTemplateSchema(templateLines []string) (*Schema, error) {
+	schema, err := parseSchema(g.config.TemplatePath, templateLines)
+	if err != nil {
+		return nil, err
+	}
+	schema.sources = g.templateSources
+	return schema, nil
+}
+
+// Schema reads the template and returns the schema declared by its annotations
+func (g *Generator) Schema() (*Schema, error) {
+	templateLines, _, err := g.readTemplateFile()
+	if err != nil {
+		return nil, err
+	}
+	return g.parseTemplateSchema(templateLines)
+}
+
+// Check validates the existing output file against the template schema without writing anything
+func (g *Generator) Check() ([]Violation, error) {
+	templateLines, _, err := g.readTemplateFile()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := g.parseTemplateSchema(templateLines)
+	if err != nil {
+		return nil, err
+	}
+
+	lines, _, err := g.readOutputFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output file: %w", err)
+	}
+	dotenv := g.config.Format == "" || g.config.Format == FormatDotenv
+	if !dotenv {
+		canonicalizeKeys(lines, g.parseTemplateInfo(templateLines))
+	}
+
+	return schema.Validate(g.config.OutputPath, lines, dotenv), nil
+}
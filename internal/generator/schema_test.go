@@ -0,0 +1,127 @@
+package generator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const schemaTemplate = `# Public URL of the API
+# @type url
+# @required
+API_URL=
+
+# @enum debug|info|warn
+LOG_LEVEL=info
+
+# @type port
+PORT=8080
+
+# @pattern ^sk_
+# @required
+STRIPE_KEY=sk_${stripe_key}
+`
+
+// TestParseSchema tests reading annotations from comment groups
+func TestParseSchema(t *testing.T) {
+	schema, err := parseSchema(".env.example", strings.Split(schemaTemplate, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	apiURL := schema.Field("API_URL")
+	if apiURL == nil || apiURL.Type != TypeURL || !apiURL.Required || apiURL.Line != 4 {
+		t.Errorf("Unexpected API_URL field: %+v", apiURL)
+	}
+	if logLevel := schema.Field("LOG_LEVEL"); len(logLevel.Enum) != 3 || logLevel.Required {
+		t.Errorf("Unexpected LOG_LEVEL field: %+v", logLevel)
+	}
+	if port := schema.Field("PORT"); port.Type != TypePort {
+		t.Errorf("Unexpected PORT field: %+v", port)
+	}
+
+	_, err = parseSchema(".env.example", []string{"# @type uuid", "ID="})
+	if err == nil || !strings.HasPrefix(err.Error(), ".env.example:1: ") {
+		t.Errorf("Expected an error pointing at the annotation, got %v", err)
+	}
+}
+
+// TestSchemaValidate tests the violations reported for each annotation
+func TestSchemaValidate(t *testing.T) {
+	schema, err := parseSchema(".env.example", strings.Split(schemaTemplate, "\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	env := "API_URL=not a url\nLOG_LEVEL=verbose\nPORT=70000\n"
+	violations := schema.Validate(".env", parseEnvLines(strings.Split(env, "\n")), true)
+
+	expected := []string{
+		`.env:1: API_URL must be of type url (.env.example:2: @type url)`,
+		`.env:2: LOG_LEVEL must be one of debug|info|warn (.env.example:6: @enum debug|info|warn)`,
+		`.env:3: PORT must be of type port (.env.example:9: @type port)`,
+		`.env: STRIPE_KEY is required but missing (.env.example:13: @required)`,
+	}
+	if len(violations) != len(expected) {
+		t.Fatalf("Expected %d violations, got %d: %v", len(expected), len(violations), violations)
+	}
+	for i, want := range expected {
+		if got := violations[i].String(); got != want {
+			t.Errorf("Violation %d:\n got %s\nwant %s", i, got, want)
+		}
+	}
+
+	valid := "API_URL=https://api.example.com\nLOG_LEVEL=warn\nPORT=443\nSTRIPE_KEY=sk_abc\n"
+	if violations := schema.Validate(".env", parseEnvLines(strings.Split(valid, "\n")), true); len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+}
+
+// TestGeneratorValidatesSchema tests that Generate writes the file and then reports violations
+func TestGeneratorValidatesSchema(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	if err := os.WriteFile(templatePath, []byte(schemaTemplate), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env")
+	config := Config{TemplatePath: templatePath, OutputPath: outputPath}
+	err = New(config).Generate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if len(validationErr.Violations) != 1 || validationErr.Violations[0].Key != "API_URL" || validationErr.Violations[0].Line != 4 {
+		t.Errorf("Expected API_URL to be reported as empty, got %v", validationErr.Violations)
+	}
+	if _, err := os.Stat(outputPath); err != nil {
+		t.Errorf("Output file should be written so it can be fixed: %v", err)
+	}
+
+	// Once the value is filled in, check and generate pass
+	content, _ := os.ReadFile(outputPath)
+	fixed := strings.Replace(string(content), "API_URL=", "API_URL=https://api.example.com", 1)
+	if err := os.WriteFile(outputPath, []byte(fixed), 0600); err != nil {
+		t.Fatalf("Failed to write .env file: %v", err)
+	}
+
+	violations, err := New(config).Check()
+	if err != nil {
+		t.Fatalf("Failed to check .env file: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("Expected no violations, got %v", violations)
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Errorf("Expected generate to pass, got %v", err)
+	}
+}
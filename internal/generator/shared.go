@@ -0,0 +1,346 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sharedNamespace prefixes placeholders whose value is shared by every template generated
+// with the same SharedValues, e.g. ${shared:jwt_secret}
+const sharedNamespace = "shared"
+
+const (
+	// SharedStoreDir is the directory holding the shared values store of a project
+	SharedStoreDir = ".genenv"
+	// sharedStoreName is the file name of the shared values store
+	sharedStoreName = "shared.json"
+	// SharedStoreMode is the permission of the shared values store
+	SharedStoreMode os.FileMode = 0600
+)
+
+// SharedEntry is a value of the shared values store
+type SharedEntry struct {
+	Name    string      `json:"-"`
+	Value   string      `json:"value"`
+	Length  int         `json:"length"`
+	Charset CharsetType `json:"charset"`
+	Updated time.Time   `json:"updated"`
+}
+
+// sharedStore is the content of the shared values store file
+type sharedStore struct {
+	Values map[string]SharedEntry `json:"values"`
+}
+
+// SharedValues holds the values of ${shared:...} placeholders so that several templates
+// agree on them
+// Values are kept in memory, or in a store file when opened with OpenSharedValues so that
+// separate runs agree too. It is safe for concurrent use by generators running in parallel.
+type SharedValues struct {
+	mu     sync.Mutex
+	values map[string]string
+
+	path        string // Store file; empty keeps values in memory
+	lockTimeout time.Duration
+}
+
+// NewSharedValues creates an empty set of shared values kept in memory
+func NewSharedValues() *SharedValues {
+	return &SharedValues{values: make(map[string]string)}
+}
+
+// OpenSharedValues creates shared values backed by the store file at path
+// The file is created by the first run that needs a shared value.
+func OpenSharedValues(path string, lockTimeout time.Duration) *SharedValues {
+	return &SharedValues{values: make(map[string]string), path: path, lockTimeout: lockTimeout}
+}
+
+// FindSharedStore returns the path of the shared values store for a directory: the store in
+// the nearest SharedStoreDir above it, or else next to the nearest .git, or else in dir itself
+func FindSharedStore(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, marker := range []string{SharedStoreDir, ".git"} {
+		for current := abs; ; current = filepath.Dir(current) {
+			if _, err := os.Stat(filepath.Join(current, marker)); err == nil {
+				return filepath.Join(current, SharedStoreDir, sharedStoreName), nil
+			}
+			if filepath.Dir(current) == current {
+				break
+			}
+		}
+	}
+	return filepath.Join(abs, SharedStoreDir, sharedStoreName), nil
+}
+
+// Path returns the store file of the shared values, or an empty string if they are in memory
+func (s *SharedValues) Path() string {
+	return s.path
+}
+
+// value returns the value of a shared placeholder, generating it the first time it is needed
+// A value generated for a store is written to it right away, under a lock, unless another run
+// wrote one in the meantime.
+func (s *SharedValues) value(name string, length int, charset CharsetType, generate func() (string, error)) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, ok := s.values[name]; ok {
+		return value, nil
+	}
+
+	var value string
+	err := s.update(name, func(store *sharedStore) (bool, error) {
+		if entry, ok := store.Values[storeName(name)]; ok {
+			value = entry.Value
+			return false, nil
+		}
+		generated, err := generate()
+		if err != nil {
+			return false, err
+		}
+		value = generated
+		store.Values[storeName(name)] = SharedEntry{Value: value, Length: length, Charset: charset, Updated: time.Now().UTC()}
+		return true, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	s.values[name] = value
+	return value, nil
+}
+
+// offer records a value found for a shared placeholder in an existing output unless one is
+// already known; a value in the store wins over the offered one
+func (s *SharedValues) offer(name, value string, charset CharsetType) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[name]; ok {
+		return nil
+	}
+
+	err := s.update(name, func(store *sharedStore) (bool, error) {
+		if entry, ok := store.Values[storeName(name)]; ok {
+			value = entry.Value
+			return false, nil
+		}
+		store.Values[storeName(name)] = SharedEntry{Value: value, Length: len(value), Charset: charset, Updated: time.Now().UTC()}
+		return true, nil
+	})
+	if err != nil {
+		return err
+	}
+	s.values[name] = value
+	return nil
+}
+
+// update looks up a value in the store and lets change modify the store, which is written
+// when change reports a modification
+// The store is read without a lock first so values already stored need no lock, which is only
+// taken, and the store read again, when the value is missing. In-memory values have an empty
+// store.
+func (s *SharedValues) update(name string, change func(store *sharedStore) (bool, error)) error {
+	if s.path == "" {
+		_, err := change(&sharedStore{Values: make(map[string]SharedEntry)})
+		return err
+	}
+
+	store, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := store.Values[storeName(name)]; ok {
+		_, err := change(store)
+		return err
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if store, err = s.read(); err != nil {
+		return err
+	}
+	changed, err := change(store)
+	if err != nil || !changed {
+		return err
+	}
+	return s.write(store)
+}
+
+// storeName returns the name of a shared placeholder in the store, without the namespace
+func storeName(name string) string {
+	return strings.TrimPrefix(name, sharedNamespace+":")
+}
+
+// read reads the store file; a missing file is an empty store
+func (s *SharedValues) read() (*sharedStore, error) {
+	store := &sharedStore{Values: make(map[string]SharedEntry)}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared values store: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse shared values store %s: %w", s.path, err)
+	}
+	if store.Values == nil {
+		store.Values = make(map[string]SharedEntry)
+	}
+	return store, nil
+}
+
+// write replaces the store file with SharedStoreMode
+func (s *SharedValues) write(store *sharedStore) error {
+	data, err := json.MarshalIndent(store, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, append(data, '\n'), SharedStoreMode); err != nil {
+		return fmt.Errorf("failed to write shared values store: %w", err)
+	}
+	return nil
+}
+
+// lock creates the store directory if needed and locks the store for writing
+// A new store directory gets a .gitignore so the values are never committed.
+func (s *SharedValues) lock() (unlock func(), err error) {
+	dir := filepath.Dir(s.path)
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create shared values store directory: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*\n"), 0644); err != nil {
+			return nil, fmt.Errorf("failed to create shared values store directory: %w", err)
+		}
+	}
+	return acquireLock(s.path+lockSuffix, s.lockTimeout, s.path)
+}
+
+// Entries returns the entries of the store, sorted by name
+func (s *SharedValues) Entries() ([]SharedEntry, error) {
+	store, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]SharedEntry, 0, len(store.Values))
+	for name, entry := range store.Values {
+		entry.Name = name
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b SharedEntry) int { return strings.Compare(a.Name, b.Name) })
+	return entries, nil
+}
+
+// Rotate replaces the value of a stored entry with a new random value of the same length and
+// character set
+// Outputs keep the previous value until they are regenerated with --force.
+func (s *SharedValues) Rotate(name string) error {
+	name = storeName(name)
+	return s.modify(name, func(store *sharedStore, entry SharedEntry) error {
+		value, err := New(Config{ValueLength: entry.Length, Charset: entry.Charset}).generateValue(placeholder{name: name})
+		if err != nil {
+			return err
+		}
+		entry.Value, entry.Updated = value, time.Now().UTC()
+		store.Values[name] = entry
+		return nil
+	})
+}
+
+// Remove deletes a stored entry; the next run that needs it generates a new value
+func (s *SharedValues) Remove(name string) error {
+	name = storeName(name)
+	return s.modify(name, func(store *sharedStore, _ SharedEntry) error {
+		delete(store.Values, name)
+		return nil
+	})
+}
+
+// modify changes an existing entry of the store under the store lock
+func (s *SharedValues) modify(name string, change func(store *sharedStore, entry SharedEntry) error) error {
+	if _, err := os.Stat(s.path); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no shared value named %s in %s", name, s.path)
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	store, err := s.read()
+	if err != nil {
+		return err
+	}
+	entry, ok := store.Values[name]
+	if !ok {
+		return fmt.Errorf("no shared value named %s in %s", name, s.path)
+	}
+	if err := change(store, entry); err != nil {
+		return err
+	}
+	return s.write(store)
+}
+
+// collectSharedValues reads the template and the existing output and offers the values of
+// shared placeholders in it, see offerSharedValues
+func (g *Generator) collectSharedValues() error {
+	if g.config.Shared == nil || g.config.Force {
+		return nil
+	}
+	templateLines, _, err := g.readTemplateFile()
+	if err != nil {
+		return err
+	}
+	lines, _, err := g.readOutputFile()
+	if err != nil {
+		// Nothing to reuse from an output that doesn't exist yet
+		return nil
+	}
+	return g.offerSharedValues(g.parseTemplateInfo(templateLines), lines)
+}
+
+// offerSharedValues records the values shared placeholders have in the existing output, so
+// the templates generated after it reuse them; --force leaves them out
+func (g *Generator) offerSharedValues(templateInfo map[string]TemplateInfo, lines []EnvLine) error {
+	if g.config.Shared == nil || g.config.Force {
+		return nil
+	}
+	for _, line := range lines {
+		info, ok := templateInfo[line.Key]
+		if line.Type != LineTypeKeyValue || !ok || !info.HasPlaceholder {
+			continue
+		}
+		placeholders, err := placeholdersIn(info.Value)
+		if err != nil {
+			return err
+		}
+		for _, p := range placeholders {
+			if !p.shared || p.file != "" {
+				continue
+			}
+			value, ok := recoverPlaceholder(info.Value, unquoteValue(line.Value), p.name)
+			if !ok {
+				continue
+			}
+			_, charset := g.placeholderSpec(p)
+			if err := g.config.Shared.offer(p.name, value, charset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
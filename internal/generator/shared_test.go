@@ -0,0 +1,180 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSharedStore tests that separate runs agree on shared values through the store file
+func TestSharedStore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"api/.env.example", "JWT_SECRET=${shared:jwt_secret:length=32}\n"},
+		[2]string{"gateway/.env.example", "JWT=${shared:jwt_secret}\nOWN=${own}\n"},
+	)
+	storePath := filepath.Join(tempDir, SharedStoreDir, sharedStoreName)
+	run := func(template string) map[string]string {
+		t.Helper()
+		output := filepath.Join(filepath.Dir(template), ".env")
+		config := Config{TemplatePath: template, OutputPath: output, Shared: OpenSharedValues(storePath, 0)}
+		if err := New(config).Generate(); err != nil {
+			t.Fatalf("Failed to generate %s: %v", output, err)
+		}
+		return readEnv(t, output)
+	}
+
+	api := run(paths[0])
+	gateway := run(paths[1])
+	if len(api["JWT_SECRET"]) != 32 || gateway["JWT"] != api["JWT_SECRET"] {
+		t.Errorf("Expected the same 32 character secret, got %q and %q", api["JWT_SECRET"], gateway["JWT"])
+	}
+
+	info, err := os.Stat(storePath)
+	if err != nil {
+		t.Fatalf("Expected the store to be created: %v", err)
+	}
+	if info.Mode().Perm() != SharedStoreMode {
+		t.Errorf("Expected store mode %o, got %o", SharedStoreMode, info.Mode().Perm())
+	}
+	if ignore, _ := os.ReadFile(filepath.Join(tempDir, SharedStoreDir, ".gitignore")); string(ignore) != "*\n" {
+		t.Errorf("Expected the store directory to ignore itself, got %q", ignore)
+	}
+
+	values := OpenSharedValues(storePath, 0)
+	entries, err := values.Entries()
+	if err != nil {
+		t.Fatalf("Failed to list entries: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "jwt_secret" || entries[0].Length != 32 || entries[0].Charset != CharsetAlphanumeric {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+
+	// --force keeps stored values so separately generated outputs stay in sync
+	config := Config{TemplatePath: paths[1], OutputPath: filepath.Join(tempDir, "gateway", ".env"), Force: true, NoBackup: true, Shared: OpenSharedValues(storePath, 0)}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to regenerate: %v", err)
+	}
+	forced := readEnv(t, config.OutputPath)
+	if forced["JWT"] != api["JWT_SECRET"] || forced["OWN"] == gateway["OWN"] {
+		t.Errorf("Expected --force to keep the shared value and regenerate the others, got %v", forced)
+	}
+
+	// Rotating replaces the value with one of the same shape
+	if err := values.Rotate("jwt_secret"); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	entries, _ = values.Entries()
+	if entries[0].Value == api["JWT_SECRET"] || len(entries[0].Value) != 32 {
+		t.Errorf("Expected a new 32 character value, got %q", entries[0].Value)
+	}
+	if err := values.Rotate("missing"); err == nil || !strings.Contains(err.Error(), "no shared value named missing") {
+		t.Errorf("Expected an error rotating a missing value, got %v", err)
+	}
+
+	if err := values.Remove("shared:jwt_secret"); err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+	if entries, _ = values.Entries(); len(entries) != 0 {
+		t.Errorf("Expected an empty store, got %+v", entries)
+	}
+}
+
+// TestSharedStoreOffer tests that existing outputs seed the store and the store wins over them
+func TestSharedStoreOffer(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	paths := writeTemplates(t, tempDir,
+		[2]string{"api/.env.example", "JWT_SECRET=Bearer ${shared:jwt_secret}\n"},
+		[2]string{"api/.env", "JWT_SECRET=Bearer existing-value\n"},
+		[2]string{"gateway/.env.example", "JWT=${shared:jwt_secret}\n"},
+	)
+	storePath := filepath.Join(tempDir, SharedStoreDir, sharedStoreName)
+
+	config := Config{TemplatePath: paths[0], OutputPath: paths[1], Shared: OpenSharedValues(storePath, 0)}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	gatewayOutput := filepath.Join(tempDir, "gateway", ".env")
+	config = Config{TemplatePath: paths[2], OutputPath: gatewayOutput, Shared: OpenSharedValues(storePath, 0)}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate: %v", err)
+	}
+	if env := readEnv(t, gatewayOutput); env["JWT"] != "existing-value" {
+		t.Errorf("Expected the value of the existing output, got %q", env["JWT"])
+	}
+}
+
+// TestSharedStoreConcurrent tests that parallel runs against one store agree on a new value
+func TestSharedStoreConcurrent(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	storePath := filepath.Join(tempDir, SharedStoreDir, sharedStoreName)
+	const runs = 8
+	results := make([]string, runs)
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Each run opens the store itself like a separate genenv process
+			values := OpenSharedValues(storePath, 0)
+			value, err := values.value("shared:token", 8, CharsetNumeric, func() (string, error) {
+				return New(Config{ValueLength: 8, Charset: CharsetNumeric}).generateValue(placeholder{name: "token"})
+			})
+			if err != nil {
+				t.Errorf("Run %d failed: %v", i, err)
+			}
+			results[i] = value
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result != results[0] {
+			t.Fatalf("Expected every run to get the same value, got %v", results)
+		}
+	}
+}
+
+// TestFindSharedStore tests locating the store of a project
+func TestFindSharedStore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	root, _ := filepath.EvalSymlinks(tempDir)
+	service := filepath.Join(root, "services", "api")
+	os.MkdirAll(service, 0755)
+
+	if path, _ := FindSharedStore(service); path != filepath.Join(service, SharedStoreDir, sharedStoreName) {
+		t.Errorf("Expected a store in the directory itself, got %s", path)
+	}
+
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	if path, _ := FindSharedStore(service); path != filepath.Join(root, SharedStoreDir, sharedStoreName) {
+		t.Errorf("Expected a store next to .git, got %s", path)
+	}
+
+	os.Mkdir(filepath.Join(root, "services", SharedStoreDir), 0700)
+	if path, _ := FindSharedStore(service); path != filepath.Join(root, "services", SharedStoreDir, sharedStoreName) {
+		t.Errorf("Expected the existing store directory, got %s", path)
+	}
+}
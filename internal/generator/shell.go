@@ -0,0 +1,337 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// shellKeyRe matches names every supported shell accepts as an environment variable
+var shellKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shellDialect describes how one shell assigns and quotes an exported variable
+type shellDialect struct {
+	assign func(key, value string) string
+	parse  func(s *shellScanner) (key, value string, err error)
+}
+
+// shellDialects maps the shell output formats to their dialects
+var shellDialects = map[Format]shellDialect{
+	FormatShell:      {assign: assignSh, parse: parseShAssignment},
+	FormatFish:       {assign: assignFish, parse: parseFishAssignment},
+	FormatPowerShell: {assign: assignPowerShell, parse: parsePowerShellAssignment},
+}
+
+// shellRenderer renders env lines as a script that exports each key when sourced
+type shellRenderer struct {
+	dialect shellDialect
+	eol     string
+}
+
+// Render writes one assignment per key, keeping comments and blank lines in place
+// Values are always single-quoted, so nothing in them is expanded by the shell.
+func (r shellRenderer) Render(lines []EnvLine) ([]byte, error) {
+	var b strings.Builder
+	for _, line := range lines {
+		switch {
+		case line.Type == LineTypeKeyValue:
+			if !shellKeyRe.MatchString(line.Key) {
+				return nil, fmt.Errorf("key %s is not a valid shell variable name", line.Key)
+			}
+			b.WriteString(r.dialect.assign(line.Key, unquoteValue(line.Value)))
+		case strings.TrimSpace(line.Raw) == "":
+		case strings.HasPrefix(strings.TrimSpace(line.Raw), "#"):
+			b.WriteString(strings.TrimSpace(line.Raw))
+		default:
+			// Unparseable template lines would be a syntax error in a script
+			b.WriteString("# " + strings.TrimSpace(line.Raw))
+		}
+		b.WriteString(r.eol)
+	}
+	return []byte(b.String()), nil
+}
+
+// Parse reads back a script written by Render, including values spanning several lines
+// Line endings are normalized to LF before parsing, including those inside quoted values.
+func (r shellRenderer) Parse(data []byte) ([]EnvLine, error) {
+	text := strings.TrimPrefix(string(data), utf8BOM)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	s := &shellScanner{text: text, line: 1}
+
+	var lines []EnvLine
+	for !s.done() {
+		s.skipBlanks()
+		switch {
+		case s.done() || s.peek() == '\n':
+			lines = append(lines, EnvLine{Type: LineTypeComment})
+		case s.peek() == '#':
+			lines = append(lines, EnvLine{Type: LineTypeComment, Raw: s.untilNewline()})
+		default:
+			line := s.line
+			key, value, err := r.dialect.parse(s)
+			if err == nil {
+				err = s.endStatement()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid script on line %d: %w", line, err)
+			}
+			lines = append(lines, parseEnvLine(key+"="+quoteValue(value)))
+		}
+		s.next()
+	}
+	return lines, nil
+}
+
+// assignSh writes a POSIX shell export, closing the quotes around each single quote
+func assignSh(key, value string) string {
+	return "export " + key + "='" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// assignFish writes a fish global export; single quotes only need \ and ' escaped
+func assignFish(key, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "'", `\'`)
+	return "set -gx " + key + " '" + value + "'"
+}
+
+// powerShellQuotes are the characters PowerShell accepts as single quotes
+const powerShellQuotes = "'‘’‚‛"
+
+// assignPowerShell writes a PowerShell environment assignment, doubling every kind of single quote
+func assignPowerShell(key, value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		if strings.ContainsRune(powerShellQuotes, c) {
+			b.WriteRune(c)
+		}
+		b.WriteRune(c)
+	}
+	return "$env:" + key + " = '" + b.String() + "'"
+}
+
+// parseShAssignment reads `[export] KEY=word`
+func parseShAssignment(s *shellScanner) (string, string, error) {
+	if s.consumeWord("export") {
+		s.skipBlanks()
+	}
+	key := s.identifier()
+	if key == "" || !s.consume("=") {
+		return "", "", fmt.Errorf("expected export KEY=value")
+	}
+
+	var b strings.Builder
+	for !s.done() && !strings.ContainsRune(" \t\n;", s.peek()) {
+		switch c := s.next(); c {
+		case '\'':
+			value, err := s.quoted('\'', 0)
+			if err != nil {
+				return "", "", err
+			}
+			b.WriteString(value)
+		case '"':
+			value, err := s.quoted('"', '\\')
+			if err != nil {
+				return "", "", err
+			}
+			b.WriteString(value)
+		case '\\':
+			// A backslash before a newline continues the line
+			if c := s.next(); c != '\n' && c != 0 {
+				b.WriteRune(c)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return key, b.String(), nil
+}
+
+// parseFishAssignment reads `set [flags] KEY word`
+func parseFishAssignment(s *shellScanner) (string, string, error) {
+	if !s.consumeWord("set") {
+		return "", "", fmt.Errorf("expected set -gx KEY value")
+	}
+	s.skipBlanks()
+	for !s.done() && s.peek() == '-' {
+		for !s.done() && !strings.ContainsRune(" \t\n", s.peek()) {
+			s.next()
+		}
+		s.skipBlanks()
+	}
+	key := s.identifier()
+	if key == "" {
+		return "", "", fmt.Errorf("expected set -gx KEY value")
+	}
+	s.skipBlanks()
+
+	var b strings.Builder
+	for !s.done() && !strings.ContainsRune(" \t\n;", s.peek()) {
+		switch c := s.next(); c {
+		case '\'':
+			value, err := s.quoted('\'', '\\')
+			if err != nil {
+				return "", "", err
+			}
+			b.WriteString(value)
+		case '"':
+			value, err := s.quoted('"', '\\')
+			if err != nil {
+				return "", "", err
+			}
+			b.WriteString(value)
+		case '\\':
+			// A backslash before a newline continues the line
+			if c := s.next(); c != '\n' && c != 0 {
+				b.WriteRune(c)
+			}
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return key, b.String(), nil
+}
+
+// parsePowerShellAssignment reads `$env:KEY = 'value'`
+func parsePowerShellAssignment(s *shellScanner) (string, string, error) {
+	if !s.consume("$env:") {
+		return "", "", fmt.Errorf("expected $env:KEY = 'value'")
+	}
+	key := s.identifier()
+	s.skipBlanks()
+	if key == "" || !s.consume("=") {
+		return "", "", fmt.Errorf("expected $env:KEY = 'value'")
+	}
+	s.skipBlanks()
+
+	if s.done() || !strings.ContainsRune(powerShellQuotes, s.peek()) {
+		return "", "", fmt.Errorf("expected a single-quoted value for %s", key)
+	}
+	s.next()
+
+	var b strings.Builder
+	for {
+		if s.done() {
+			return "", "", fmt.Errorf("unterminated quoted value")
+		}
+		c := s.next()
+		if strings.ContainsRune(powerShellQuotes, c) {
+			if s.done() || !strings.ContainsRune(powerShellQuotes, s.peek()) {
+				return key, b.String(), nil
+			}
+			c = s.next()
+		}
+		b.WriteRune(c)
+	}
+}
+
+// shellScanner walks a script one rune at a time, counting lines for error messages
+type shellScanner struct {
+	text string
+	pos  int
+	line int
+}
+
+// done reports whether the whole script has been read
+func (s *shellScanner) done() bool {
+	return s.pos >= len(s.text)
+}
+
+// peek returns the next rune without consuming it
+func (s *shellScanner) peek() rune {
+	c, _ := utf8.DecodeRuneInString(s.text[s.pos:])
+	return c
+}
+
+// next consumes and returns the next rune, or 0 at the end of the script
+func (s *shellScanner) next() rune {
+	if s.done() {
+		return 0
+	}
+	c, size := utf8.DecodeRuneInString(s.text[s.pos:])
+	s.pos += size
+	if c == '\n' {
+		s.line++
+	}
+	return c
+}
+
+// skipBlanks consumes spaces and tabs
+func (s *shellScanner) skipBlanks() {
+	for !s.done() && (s.peek() == ' ' || s.peek() == '\t') {
+		s.next()
+	}
+}
+
+// consume consumes prefix if the script continues with it
+func (s *shellScanner) consume(prefix string) bool {
+	if !strings.HasPrefix(s.text[s.pos:], prefix) {
+		return false
+	}
+	s.pos += len(prefix)
+	return true
+}
+
+// consumeWord consumes word if it is followed by a blank
+func (s *shellScanner) consumeWord(word string) bool {
+	rest := s.text[s.pos:]
+	if !strings.HasPrefix(rest, word+" ") && !strings.HasPrefix(rest, word+"\t") {
+		return false
+	}
+	s.pos += len(word)
+	return true
+}
+
+// identifier consumes a variable name
+func (s *shellScanner) identifier() string {
+	start := s.pos
+	for !s.done() {
+		c := s.peek()
+		if c != '_' && !('A' <= c && c <= 'Z') && !('a' <= c && c <= 'z') && !(s.pos > start && '0' <= c && c <= '9') {
+			break
+		}
+		s.next()
+	}
+	return s.text[start:s.pos]
+}
+
+// quoted consumes a quoted string up to the closing quote, which has already been opened
+// With an escape rune set, it escapes the quote and itself; other escapes are kept as written.
+func (s *shellScanner) quoted(quote, escape rune) (string, error) {
+	var b strings.Builder
+	for !s.done() {
+		c := s.next()
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case escape != 0 && c == escape && (s.peek() == quote || s.peek() == escape):
+			b.WriteRune(s.next())
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated quoted value")
+}
+
+// untilNewline consumes the rest of the current line
+func (s *shellScanner) untilNewline() string {
+	start := s.pos
+	for !s.done() && s.peek() != '\n' {
+		s.next()
+	}
+	return s.text[start:s.pos]
+}
+
+// endStatement consumes an optional semicolon and comment after a statement
+func (s *shellScanner) endStatement() error {
+	s.skipBlanks()
+	s.consume(";")
+	s.skipBlanks()
+	if !s.done() && s.peek() == '#' {
+		s.untilNewline()
+	}
+	if !s.done() && s.peek() != '\n' {
+		return fmt.Errorf("unexpected %q after assignment", s.untilNewline())
+	}
+	return nil
+}
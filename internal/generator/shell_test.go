@@ -0,0 +1,184 @@
+package generator
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// trickyShellValues are values that break naive quoting in at least one shell
+var trickyShellValues = []string{
+	"",
+	"plain",
+	"with space",
+	"it's",
+	`back\slash\`,
+	`\'`,
+	`"double"`,
+	"$HOME `id` $(id) %PATH%",
+	"line1\nline2",
+	"semi;colon # hash",
+	"‘typographic’ ‚quotes‛",
+	"'''",
+}
+
+// TestShellRendererRoundTrip tests that every dialect parses back exactly what it rendered
+func TestShellRendererRoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatShell, FormatFish, FormatPowerShell} {
+		renderer := shellRenderer{dialect: shellDialects[format], eol: "\n"}
+
+		lines := []EnvLine{parseEnvLine("# Secrets"), parseEnvLine("")}
+		for i, value := range trickyShellValues {
+			lines = append(lines, parseEnvLine("KEY_"+string(rune('A'+i))+"="+quoteValue(value)))
+		}
+
+		data, err := renderer.Render(lines)
+		if err != nil {
+			t.Fatalf("%s: failed to render: %v", format, err)
+		}
+		parsed, err := renderer.Parse(data)
+		if err != nil {
+			t.Fatalf("%s: failed to parse rendered script: %v\n%s", format, err, data)
+		}
+
+		if len(parsed) != len(lines) {
+			t.Fatalf("%s: expected %d lines, got %d:\n%s", format, len(lines), len(parsed), data)
+		}
+		if parsed[0].Raw != "# Secrets" || parsed[1].Raw != "" {
+			t.Errorf("%s: comment and blank line were not preserved: %q, %q", format, parsed[0].Raw, parsed[1].Raw)
+		}
+		for i, value := range trickyShellValues {
+			if got := unquoteValue(parsed[i+2].Value); got != value {
+				t.Errorf("%s: value %q came back as %q", format, value, got)
+			}
+		}
+	}
+}
+
+// TestShellRendererSourcedByShell tests that sh sees the exact values after sourcing the script
+func TestShellRendererSourcedByShell(t *testing.T) {
+	shell, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	renderer := shellRenderer{dialect: shellDialects[FormatShell], eol: "\n"}
+	for _, value := range trickyShellValues {
+		data, err := renderer.Render([]EnvLine{parseEnvLine("VALUE=" + quoteValue(value))})
+		if err != nil {
+			t.Fatalf("Failed to render: %v", err)
+		}
+		scriptPath := filepath.Join(tempDir, "env.sh")
+		if err := os.WriteFile(scriptPath, data, 0600); err != nil {
+			t.Fatalf("Failed to write script: %v", err)
+		}
+
+		output, err := exec.Command(shell, "-c", `. "$1" && printf %s "$VALUE"`, "sh", scriptPath).Output()
+		if err != nil {
+			t.Fatalf("Failed to source script for %q: %v", value, err)
+		}
+		if string(output) != value {
+			t.Errorf("sh saw %q instead of %q", output, value)
+		}
+	}
+}
+
+// TestShellRendererRejectsInvalidKeys tests that keys no shell can export are an error
+func TestShellRendererRejectsInvalidKeys(t *testing.T) {
+	renderer := shellRenderer{dialect: shellDialects[FormatShell], eol: "\n"}
+	if _, err := renderer.Render([]EnvLine{parseEnvLine("APP.NAME=demo")}); err == nil {
+		t.Error("Render should reject keys that are not valid shell variable names")
+	}
+}
+
+// TestShellRendererParse tests reading hand-edited scripts
+func TestShellRendererParse(t *testing.T) {
+	tests := []struct {
+		format Format
+		script string
+		want   string
+	}{
+		{FormatShell, "export VALUE='a'\\''b'\r\n", "a'b"},
+		{FormatShell, "VALUE=\"x\\\"y\"; # note\n", `x"y`},
+		{FormatShell, "export VALUE=bare\n", "bare"},
+		{FormatFish, "set -x -g VALUE 'a\\'b'\n", "a'b"},
+		{FormatPowerShell, "$env:VALUE='a''b'\n", "a'b"},
+	}
+
+	for _, tt := range tests {
+		renderer := shellRenderer{dialect: shellDialects[tt.format], eol: "\n"}
+		lines, err := renderer.Parse([]byte(tt.script))
+		if err != nil {
+			t.Errorf("%s: failed to parse %q: %v", tt.format, tt.script, err)
+			continue
+		}
+		if len(lines) != 1 || lines[0].Key != "VALUE" || unquoteValue(lines[0].Value) != tt.want {
+			t.Errorf("%s: parsing %q gave %+v, want VALUE=%q", tt.format, tt.script, lines, tt.want)
+		}
+	}
+
+	renderer := shellRenderer{dialect: shellDialects[FormatShell], eol: "\n"}
+	if _, err := renderer.Parse([]byte("export VALUE='unterminated\n")); err == nil {
+		t.Error("Parse should reject unterminated quotes")
+	}
+}
+
+// TestGeneratorShellFormat tests generating a sourceable script and preserving values on re-runs
+func TestGeneratorShellFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := "# Database\nDB_HOST=localhost\nDB_PASSWORD=${db_password}\n"
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, ".env.fish")
+	config := Config{
+		TemplatePath: templatePath,
+		OutputPath:   outputPath,
+		Format:       FormatFish,
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate fish script: %v", err)
+	}
+
+	first, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !strings.HasPrefix(string(first), "# Database\nset -gx DB_HOST 'localhost'\nset -gx DB_PASSWORD '") {
+		t.Errorf("Unexpected fish script:\n%s", first)
+	}
+
+	templateContent += "API_KEY=${api_key}\n"
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate fish script: %v", err)
+	}
+
+	second, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !strings.HasPrefix(string(second), string(first)) {
+		t.Errorf("Existing values were not preserved:\n%s\nvs\n%s", first, second)
+	}
+	if !strings.Contains(string(second), "set -gx API_KEY '") {
+		t.Errorf("New key was not added:\n%s", second)
+	}
+}
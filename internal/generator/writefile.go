@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultFileMode is the permission used for newly created output files
+const DefaultFileMode os.FileMode = 0600
+
+// writeFileAtomic replaces the file at path with data without ever exposing a partial file
+// The data is written to a temp file in the same directory, synced and renamed over the target.
+// Symlinks are followed so the link itself stays in place and its target is updated.
+// An explicit mode wins; otherwise the existing file's mode is kept, falling back to DefaultFileMode.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	target, err := resolveSymlink(path)
+	if err != nil {
+		return err
+	}
+
+	existing, err := os.Stat(target)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	perm := mode.Perm()
+	if mode == 0 {
+		perm = DefaultFileMode
+		if existing != nil {
+			perm = existing.Mode().Perm()
+		}
+	}
+
+	dir, base := filepath.Split(target)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if existing != nil {
+		if err := preserveOwner(tmp, existing); err != nil {
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, target); err != nil {
+		return err
+	}
+	committed = true
+
+	// Persist the rename itself; failure here does not invalidate the written data
+	_ = syncDir(dir)
+
+	return nil
+}
+
+// resolveSymlink returns the final target of path if it is a symlink, or path itself
+// Dangling links resolve to the path they point at so the target gets created
+func resolveSymlink(path string) (string, error) {
+	for range 255 {
+		info, err := os.Lstat(path)
+		if os.IsNotExist(err) {
+			return path, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		link, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if !filepath.IsAbs(link) {
+			link = filepath.Join(filepath.Dir(path), link)
+		}
+		path = link
+	}
+
+	return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+}
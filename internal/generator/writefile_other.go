@@ -0,0 +1,15 @@
+//go:build !unix
+
+package generator
+
+import "os"
+
+// preserveOwner is a no-op on platforms without POSIX ownership
+func preserveOwner(file *os.File, existing os.FileInfo) error {
+	return nil
+}
+
+// syncDir is a no-op on platforms that cannot sync directories
+func syncDir(dir string) error {
+	return nil
+}
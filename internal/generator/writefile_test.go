@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFileAtomicDefaultMode tests that new files are only readable by the owner
+func TestWriteFileAtomicDefaultMode(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, ".env")
+	if err := writeFileAtomic(path, []byte("KEY=value\n"), 0); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != DefaultFileMode {
+		t.Errorf("Expected mode %o, got %o", DefaultFileMode, info.Mode().Perm())
+	}
+
+	// No temp files should be left behind
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the output file, found %d entries", len(entries))
+	}
+}
+
+// TestWriteFileAtomicPreservesMode tests that an existing file keeps its permissions
+func TestWriteFileAtomicPreservesMode(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	path := filepath.Join(tempDir, ".env")
+	if err := os.WriteFile(path, []byte("OLD=1\n"), 0640); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+	if err := os.Chmod(path, 0640); err != nil {
+		t.Fatalf("Failed to chmod existing file: %v", err)
+	}
+
+	if err := writeFileAtomic(path, []byte("NEW=1\n"), 0); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected existing mode 640 to be kept, got %o", info.Mode().Perm())
+	}
+
+	// An explicit mode overrides the existing one
+	if err := writeFileAtomic(path, []byte("NEW=2\n"), 0604); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	info, _ = os.Stat(path)
+	if info.Mode().Perm() != 0604 {
+		t.Errorf("Expected explicit mode 604, got %o", info.Mode().Perm())
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != "NEW=2\n" {
+		t.Errorf("Unexpected content: %q", content)
+	}
+}
+
+// TestWriteFileAtomicFollowsSymlink tests that the symlink is kept and its target is updated
+func TestWriteFileAtomicFollowsSymlink(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	target := filepath.Join(tempDir, "shared.env")
+	if err := os.WriteFile(target, []byte("OLD=1\n"), 0600); err != nil {
+		t.Fatalf("Failed to write target file: %v", err)
+	}
+
+	link := filepath.Join(tempDir, ".env")
+	if err := os.Symlink("shared.env", link); err != nil {
+		t.Skipf("Symlinks not supported: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("NEW=1\n"), 0); err != nil {
+		t.Fatalf("Failed to write through symlink: %v", err)
+	}
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		t.Fatalf("Failed to stat link: %v", err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Error("Symlink was replaced by a regular file")
+	}
+
+	content, _ := os.ReadFile(target)
+	if string(content) != "NEW=1\n" {
+		t.Errorf("Symlink target was not updated, got %q", content)
+	}
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package generator
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// preserveOwner copies the owner and group of an existing file onto file
+// Lacking permission to do so is not an error; the file then keeps the caller's ownership
+func preserveOwner(file *os.File, existing os.FileInfo) error {
+	stat, ok := existing.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+
+	if err := file.Chown(int(stat.Uid), int(stat.Gid)); err != nil && !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+
+	return nil
+}
+
+// syncDir flushes directory metadata so a completed rename survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
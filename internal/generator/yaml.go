@@ -0,0 +1,268 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlRenderer renders env lines as a YAML mapping, nested by separator when one is set
+// Only the small YAML subset genenv writes is read back: block mappings of scalar values.
+type yamlRenderer struct {
+	separator string
+	comments  bool // Keep template comments as YAML comments
+	eol       string
+}
+
+// Render writes the keys in order, with their comment lines when comments are enabled
+func (r yamlRenderer) Render(lines []EnvLine) ([]byte, error) {
+	root, err := buildValueTree(lines, r.separator)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeYAMLMapping(&buf, root, "", r.comments)
+	if r.comments {
+		for _, comment := range root.comments {
+			buf.WriteString(comment + "\n")
+		}
+	}
+
+	return withLineEndings(buf.Bytes(), r.eol), nil
+}
+
+// Parse reads a YAML mapping back into env lines, keeping comments so they survive re-runs
+func (r yamlRenderer) Parse(data []byte) ([]EnvLine, error) {
+	root, err := parseYAMLMapping(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML output file: %w", err)
+	}
+	return flattenValueTree(root, r.separator), nil
+}
+
+// yamlPlainKeyRe matches keys that can be written without quotes
+var yamlPlainKeyRe = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+// yamlReservedWords are plain scalars YAML 1.1 parsers read as booleans or null
+var yamlReservedWords = map[string]bool{
+	"y": true, "yes": true, "n": true, "no": true, "true": true, "false": true,
+	"on": true, "off": true, "null": true, "~": true,
+}
+
+// writeYAMLMapping writes the children of node as an indented block mapping
+func writeYAMLMapping(buf *bytes.Buffer, node *valueNode, indent string, comments bool) {
+	for _, child := range node.children {
+		if comments {
+			for _, comment := range child.comments {
+				buf.WriteString(indent + comment + "\n")
+			}
+		}
+
+		buf.WriteString(indent + yamlKey(child.key) + ":")
+		switch {
+		case child.branch && len(child.children) == 0:
+			buf.WriteString(" {}\n")
+		case child.branch:
+			buf.WriteString("\n")
+			writeYAMLMapping(buf, child, indent+"  ", comments)
+		default:
+			// JSON strings are valid YAML double-quoted scalars and never change type
+			buf.WriteString(" ")
+			buf.Write(marshalJSONString(child.value))
+			buf.WriteString("\n")
+		}
+	}
+}
+
+// yamlKey quotes a mapping key when it would not survive as a plain scalar
+func yamlKey(key string) string {
+	if yamlPlainKeyRe.MatchString(key) && !yamlReservedWords[strings.ToLower(key)] {
+		return key
+	}
+	return string(marshalJSONString(key))
+}
+
+// parseYAMLMapping parses a block mapping of scalars into a tree
+// Comment lines are attached to the key that follows them, like buildValueTree does.
+func parseYAMLMapping(content string) (*valueNode, error) {
+	type frame struct {
+		node   *valueNode
+		indent int
+	}
+
+	root := &valueNode{branch: true}
+	stack := []frame{{node: root, indent: -1}}
+	explicitEmpty := make(map[*valueNode]bool)
+	var pending []string
+	sawKey := false
+
+	for i, rawLine := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		line := strings.TrimRight(rawLine, "\r")
+		if i == 0 {
+			line = strings.TrimPrefix(line, utf8BOM)
+		}
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			continue
+		case strings.HasPrefix(trimmed, "#"):
+			pending = append(pending, trimmed)
+			continue
+		case trimmed == "---" && !sawKey:
+			continue
+		case trimmed == "---" || trimmed == "...":
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", lineNo)
+		case strings.HasPrefix(trimmed, "- ") || trimmed == "-":
+			return nil, fmt.Errorf("line %d: sequences are not supported", lineNo)
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+
+		key, rest, err := splitYAMLKey(trimmed)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		if !parent.branch {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+		}
+
+		child := &valueNode{key: key, comments: pending}
+		pending = nil
+		parent.children = append(parent.children, child)
+		sawKey = true
+
+		switch strings.TrimSpace(rest) {
+		case "":
+			// A nested mapping may follow; without children this is an empty value
+			child.branch = true
+			stack = append(stack, frame{node: child, indent: indent})
+		case "{}":
+			child.branch = true
+			explicitEmpty[child] = true
+		default:
+			value, err := parseYAMLScalar(rest)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			child.value = value
+		}
+	}
+
+	var settle func(node *valueNode)
+	settle = func(node *valueNode) {
+		for _, child := range node.children {
+			if child.branch && len(child.children) == 0 && !explicitEmpty[child] {
+				child.branch = false
+			}
+			settle(child)
+		}
+	}
+	settle(root)
+	root.comments = pending
+
+	return root, nil
+}
+
+// splitYAMLKey splits "key: rest" into its key and the text after the colon
+func splitYAMLKey(line string) (key, rest string, err error) {
+	if line[0] == '"' || line[0] == '\'' {
+		end := 1
+		for ; end < len(line); end++ {
+			if line[end] == '\\' && line[0] == '"' {
+				end++
+				continue
+			}
+			if line[end] == line[0] {
+				break
+			}
+		}
+		if end >= len(line) {
+			return "", "", fmt.Errorf("unterminated quoted key")
+		}
+		key, err = parseYAMLScalar(line[:end+1])
+		if err != nil {
+			return "", "", err
+		}
+		after := strings.TrimLeft(line[end+1:], " ")
+		if !strings.HasPrefix(after, ":") {
+			return "", "", fmt.Errorf("expected ':' after key %s", key)
+		}
+		return key, after[1:], nil
+	}
+
+	idx := strings.Index(line, ": ")
+	if idx < 0 {
+		if !strings.HasSuffix(line, ":") {
+			return "", "", fmt.Errorf("expected 'key: value'")
+		}
+		idx = len(line) - 1
+	}
+	return strings.TrimSpace(line[:idx]), line[idx+1:], nil
+}
+
+// parseYAMLScalar parses a single-line scalar value
+func parseYAMLScalar(text string) (string, error) {
+	value := strings.TrimSpace(text)
+	if value == "" {
+		return "", nil
+	}
+
+	switch value[0] {
+	case '"':
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated double-quoted value")
+		}
+		var decoded string
+		if err := json.Unmarshal([]byte(value[:end+1]), &decoded); err == nil {
+			return decoded, nil
+		}
+		decoded, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted value %s", value[:end+1])
+		}
+		return decoded, nil
+	case '\'':
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			if value[i] == '\'' {
+				if i+1 < len(value) && value[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				return b.String(), nil
+			}
+			b.WriteByte(value[i])
+		}
+		return "", fmt.Errorf("unterminated single-quoted value")
+	case '|', '>':
+		return "", fmt.Errorf("block scalars are not supported")
+	case '[', '{':
+		return "", fmt.Errorf("flow collections are not supported")
+	case '&', '*', '!':
+		return "", fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	if idx := strings.Index(value, " #"); idx >= 0 {
+		value = strings.TrimSpace(value[:idx])
+	}
+	if value == "~" || value == "null" {
+		return "", nil
+	}
+	return value, nil
+}
@@ -0,0 +1,112 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGeneratorYAMLFormat tests rendering YAML with comments and preserving values on re-runs
+func TestGeneratorYAMLFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "genenv-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	templatePath := filepath.Join(tempDir, ".env.example")
+	templateContent := `# Database configuration
+DB__HOST=localhost
+DB__PASSWORD=${db_password}
+
+# Feature flags
+DEBUG=true`
+	if err := os.WriteFile(templatePath, []byte(templateContent), 0644); err != nil {
+		t.Fatalf("Failed to write template file: %v", err)
+	}
+
+	outputPath := filepath.Join(tempDir, "env.yaml")
+	config := Config{
+		TemplatePath:  templatePath,
+		OutputPath:    outputPath,
+		Format:        FormatYAML,
+		NestSeparator: "__",
+		YAMLComments:  true,
+	}
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to generate YAML file: %v", err)
+	}
+
+	content, _ := os.ReadFile(outputPath)
+	for _, want := range []string{"# Database configuration\ndb:\n  host: \"localhost\"\n  password: \"", "# Feature flags\ndebug: \"true\"\n"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected YAML to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	root, err := parseYAMLMapping(string(content))
+	if err != nil {
+		t.Fatalf("Generated YAML does not parse: %v", err)
+	}
+	password := root.child("db").child("password").value
+
+	// Re-running preserves the values read back from YAML
+	if err := New(config).Generate(); err != nil {
+		t.Fatalf("Failed to re-generate YAML file: %v", err)
+	}
+	again, _ := os.ReadFile(outputPath)
+	if string(again) != string(content) {
+		t.Errorf("Re-run changed the YAML file:\n%s\nvs\n%s", content, again)
+	}
+	if password == "" || !strings.Contains(string(again), password) {
+		t.Error("Existing password was not preserved")
+	}
+}
+
+// TestParseYAMLMapping tests the supported YAML subset and rejection of the rest
+func TestParseYAMLMapping(t *testing.T) {
+	content := `---
+# comment
+plain: value # trailing comment
+quoted: "a \"b\"\n"
+single: 'it''s'
+number: 42
+empty:
+nested:
+  deeper:
+    key: "v"
+null_value: ~
+`
+	root, err := parseYAMLMapping(content)
+	if err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+
+	want := map[string]string{
+		"plain":      "value",
+		"quoted":     "a \"b\"\n",
+		"single":     "it's",
+		"number":     "42",
+		"empty":      "",
+		"null_value": "",
+	}
+	for key, value := range want {
+		node := root.child(key)
+		if node == nil || node.branch || node.value != value {
+			t.Errorf("%s: expected %q, got %+v", key, value, node)
+		}
+	}
+	if root.child("nested").child("deeper").child("key").value != "v" {
+		t.Error("Nested value was not parsed")
+	}
+	if len(root.child("plain").comments) != 1 {
+		t.Error("Comment was not attached to the following key")
+	}
+
+	for _, invalid := range []string{"- item\n", "key: |\n  text\n", "a: 1\n---\nb: 2\n", "just text\n"} {
+		if _, err := parseYAMLMapping(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runLint implements `genenv lint`, which reports mistakes in a template
+// The exit code is 1 when an error is found, or any diagnostic with --strict.
+func runLint(args []string) int {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+
+	jsonOutput := fs.Bool("json", false, "Print diagnostics as a JSON array")
+	strict := fs.Bool("strict", false, "Exit with an error on warnings too")
+	maxLineSize := fs.Int("max-line-size", 0, "Maximum length of a single line in bytes (default: unlimited)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv lint [options] [template-file]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv lint\n")
+		fmt.Fprintf(os.Stderr, "  genenv lint --json .env.example\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	templatePath := ".env.example"
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		templatePath = fs.Arg(0)
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	config, err := withProject(generator.Config{
+		TemplatePath: templatePath,
+		MaxLineSize:  *maxLineSize,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	gen := generator.New(config)
+	diagnostics, err := gen.Lint()
+	if err != nil {
+		fmt.Printf("Error reading template file: %v\n", err)
+		return 1
+	}
+
+	if *jsonOutput {
+		if diagnostics == nil {
+			diagnostics = []generator.Diagnostic{}
+		}
+		data, err := json.MarshalIndent(diagnostics, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding diagnostics: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, diagnostic := range diagnostics {
+			fmt.Println(diagnostic)
+		}
+		if len(diagnostics) == 0 {
+			fmt.Printf("No problems found in %s\n", templatePath)
+		}
+	}
+
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == generator.SeverityError || *strict {
+			return 1
+		}
+	}
+	return 0
+}
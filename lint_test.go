@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLintCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "EMPTY=${}\nAPI_KEY=literal\n")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "lint", template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, template+":1:7: error: ")
+	assertContains(t, stdout, template+":2:9: warning: ")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "lint", "--json", template)
+	assertExitCode(t, exitCode, 1)
+
+	var diagnostics []map[string]any
+	if err := json.Unmarshal([]byte(stdout), &diagnostics); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v\n%s", err, stdout)
+	}
+	if len(diagnostics) != 2 || diagnostics[0]["code"] != "empty-placeholder" || diagnostics[1]["severity"] != "warning" {
+		t.Errorf("Unexpected JSON diagnostics: %v", diagnostics)
+	}
+}
+
+func TestLintCommand_Warnings(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := filepath.Join(t.TempDir(), ".env.example")
+	os.WriteFile(template, []byte("API_KEY=literal\n"), 0644)
+
+	// Warnings alone only fail with --strict
+	exitCode, _, _ := runGenenv(t, binary, "lint", template)
+	assertExitCode(t, exitCode, 0)
+
+	exitCode, _, _ = runGenenv(t, binary, "lint", "--strict", template)
+	assertExitCode(t, exitCode, 1)
+
+	os.WriteFile(template, []byte("API_KEY=${api_key}\n"), 0644)
+	exitCode, stdout, _ := runGenenv(t, binary, "lint", template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "No problems found")
+}
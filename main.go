@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/yashikota/genenv/internal/generator"
@@ -14,15 +18,49 @@ const (
 	Version = "1.1.0"
 )
 
+// commands maps subcommand names to their entry points, which return the exit code
+var commands = map[string]func(args []string) int{
+	"check":   runCheck,
+	"codegen": runCodegen,
+	"config":  runConfig,
+	"docs":    runDocs,
+	"init":    runInit,
+	"lint":    runLint,
+	"restore": runRestore,
+	"shared":  runShared,
+}
+
 func main() {
+	// Dispatch subcommands before the generate flags are parsed
+	if len(os.Args) > 1 {
+		if run, ok := commands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	force := flag.Bool("force", false, "Force regenerate all values including existing ones")
 	flag.BoolVar(force, "f", false, "Force regenerate all values including existing ones")
 
 	yes := flag.Bool("yes", false, "Skip confirmation prompt when using --force")
 	flag.BoolVar(yes, "y", false, "Skip confirmation prompt when using --force")
 
-	output := flag.String("output", ".env", "Output file path")
-	flag.StringVar(output, "o", ".env", "Output file path")
+	output := flag.String("output", ".env", "Output file path (default depends on --format)")
+	flag.StringVar(output, "o", ".env", "Output file path (default depends on --format)")
+
+	format := flag.String("format", "dotenv", "Output format: dotenv, json, yaml, k8s-secret, k8s-split, sh, fish, powershell")
+	nest := flag.String("nest", "", "Nest keys by this separator in json/yaml output, e.g. __ turns DB__HOST into db.host")
+	yamlComments := flag.Bool("yaml-comments", false, "Keep template comments as YAML comments")
+	manifestName := flag.String("name", generator.DefaultManifestName, "metadata.name of k8s-secret/k8s-split manifests")
+	manifestNamespace := flag.String("namespace", "", "metadata.namespace of k8s-secret/k8s-split manifests")
+	dialect := flag.String("dialect", "dotenv", "Quoting rules of the dotenv output: dotenv, docker, compose, systemd")
+	profile := flag.String("profile", "", "Use the template lines of this profile, e.g. dev or staging (default output: .env.<profile>)")
+
+	recursive := flag.Bool("recursive", false, "Generate every template under a directory, skipping .gitignore'd paths; -o is relative to each template")
+	pattern := flag.String("pattern", generator.DefaultTemplatePattern, "Glob of the templates --recursive looks for")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "Number of templates --recursive generates at once")
+	configPath := flag.String("config", "", "Project configuration file (default: "+strings.Join(generator.ProjectConfigNames, ", ")+" found upward, or $"+configEnv+")")
+	hooks := flag.Bool("hooks", false, "Run the pre and post hooks of the project configuration")
+	sharedStore := flag.String("shared-store", "", "Store of ${shared:...} values (default: "+generator.SharedStoreDir+"/shared.json in the project root)")
 
 	length := flag.Int("length", 24, "Length of generated random values")
 	flag.IntVar(length, "l", 24, "Length of generated random values")
@@ -30,6 +68,22 @@ func main() {
 	charset := flag.String("charset", "alphanumeric", "Character set for generated values: alphanumeric, alphabetic, uppercase, lowercase, numeric")
 	flag.StringVar(charset, "c", "alphanumeric", "Character set for generated values: alphanumeric, alphabetic, uppercase, lowercase, numeric")
 
+	mode := flag.String("mode", "", "Permissions of the output file in octal, e.g. 0600 (default: keep existing, 0600 for new files)")
+
+	noBackup := flag.Bool("no-backup", false, "Do not back up the output file before --force overwrites it")
+	keepBackups := flag.Int("keep-backups", generator.DefaultBackupRetention, "Number of backups to keep per output file")
+
+	eol := flag.String("eol", "auto", "Line endings of the output file: auto, lf, crlf")
+
+	maxLineSize := flag.Int("max-line-size", 0, "Maximum length of a single line in bytes (default: unlimited)")
+
+	lockTimeout := flag.Duration("lock-timeout", generator.DefaultLockTimeout, "How long to wait for another genenv run writing the same output")
+
+	masterKeyFile := flag.String("master-key-file", "", "Derive values from the master key stored in this file")
+	masterKeyEnv := flag.String("master-key-env", "", "Derive values from the master key stored in this environment variable")
+	deriveNamespace := flag.String("derive-namespace", generator.DefaultDeriveNamespace, "Namespace mixed into derived values")
+	deriveVersion := flag.String("derive-version", generator.DefaultDeriveVersion, "Version label for derived values (bump to rotate)")
+
 	version := flag.Bool("version", false, "Show version information")
 	flag.BoolVar(version, "v", false, "Show version information")
 
@@ -39,18 +93,34 @@ func main() {
 	// Custom usage function
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "genenv - A tool to generate .env files from templates\n\n")
-		fmt.Fprintf(os.Stderr, "Usage: genenv [options] <template-file>\n\n")
+		fmt.Fprintf(os.Stderr, "Usage: genenv [options] <template-file> [overlay-template...]\n")
+		fmt.Fprintf(os.Stderr, "       genenv --recursive [--pattern <glob>] [options] [dir]\n")
+		fmt.Fprintf(os.Stderr, "       genenv init [--from <env-file>] [options]\n")
+		fmt.Fprintf(os.Stderr, "       genenv lint [--json] [template-file]\n")
+		fmt.Fprintf(os.Stderr, "       genenv check [-o <env-file>] [template-file...]\n")
+		fmt.Fprintf(os.Stderr, "       genenv codegen go [--package <name>] [-o <file>] [template-file]\n")
+		fmt.Fprintf(os.Stderr, "       genenv codegen ts [--schema zod|valibot] [-o <file>] [template-file]\n")
+		fmt.Fprintf(os.Stderr, "       genenv docs [--format markdown|html] [--update <file>] [template-file]\n")
+		fmt.Fprintf(os.Stderr, "       genenv restore [--list|--latest|<id>] [options]\n")
+		fmt.Fprintf(os.Stderr, "       genenv shared list|rotate|rm [name...]\n")
+		fmt.Fprintf(os.Stderr, "       genenv config show [options] [template-file]\n\n")
 		fmt.Fprintf(os.Stderr, "Options:\n")
 		flag.PrintDefaults()
 		fmt.Fprintf(os.Stderr, "\nExamples:\n")
 		fmt.Fprintf(os.Stderr, "  genenv .env.example\n")
 		fmt.Fprintf(os.Stderr, "  genenv .env.example --output .env.production\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.base.example .env.api.example -o .env\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --profile staging\n")
+		fmt.Fprintf(os.Stderr, "  genenv --recursive services --jobs 4\n")
 		fmt.Fprintf(os.Stderr, "  genenv .env.example --length 32 --charset numeric\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --format yaml --nest __\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --format k8s-secret --name app-env --namespace dev\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --format sh && . ./.env.sh\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --dialect compose\n")
+		fmt.Fprintf(os.Stderr, "  genenv .env.example --master-key-file ~/.config/genenv/master.key\n")
 	}
 
-	reorderArgs()
-
-	flag.Parse()
+	flag.CommandLine.Parse(reorderArgs(flag.CommandLine, os.Args[1:]))
 
 	// Show version if requested
 	if *version {
@@ -64,13 +134,41 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Settings not given as flags come from GENENV_* variables and the project configuration
+	project, err := loadProject(*configPath)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	resolved, err := resolveSettings(flag.CommandLine, project, flag.Args(), *recursive)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	hookProject, err := trustedHooks(project, *hooks)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Get template file path from arguments
 	args := flag.Args()
+	if template := settingValue(resolved, "template"); len(args) == 0 && !*recursive && template != "" {
+		args = []string{template}
+	}
+	if *recursive {
+		if len(args) > 1 {
+			fmt.Printf("Error: --recursive takes a single directory\n")
+			os.Exit(1)
+		}
+		args = append(args, ".")[:1]
+	}
 	if len(args) < 1 {
 		flag.Usage()
 		os.Exit(0)
 	}
 	templatePath := args[0]
+	overlays := args[1:]
 
 	// Validate charset
 	charsetType := generator.CharsetType(*charset)
@@ -79,13 +177,112 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Validate output format
+	formatType := generator.Format(*format)
+	if !isValidFormat(formatType) {
+		fmt.Printf("Error: Invalid format '%s'. Valid options are: dotenv, json, yaml, k8s-secret, k8s-split, sh, fish, powershell\n", *format)
+		os.Exit(1)
+	}
+	if *profile != "" && !generator.IsValidProfile(*profile) {
+		fmt.Printf("Error: Invalid profile '%s'. Use letters, digits, _ and -\n", *profile)
+		os.Exit(1)
+	}
+	if !flagPassed("output", "o") {
+		*output = profileOutputPath(defaultOutputPath(formatType), *profile)
+	}
+
+	// Validate dialect
+	dialectType := generator.Dialect(*dialect)
+	if !isValidDialect(dialectType) {
+		fmt.Printf("Error: Invalid dialect '%s'. Valid options are: dotenv, docker, compose, systemd\n", *dialect)
+		os.Exit(1)
+	}
+	if dialectType != generator.DialectDotenv && formatType != generator.FormatDotenv {
+		fmt.Printf("Error: --dialect only applies to the dotenv format\n")
+		os.Exit(1)
+	}
+
+	// Validate line endings
+	eolStyle := generator.EOLStyle(*eol)
+	if !isValidEOL(eolStyle) {
+		fmt.Printf("Error: Invalid eol '%s'. Valid options are: auto, lf, crlf\n", *eol)
+		os.Exit(1)
+	}
+
+	// Validate file mode
+	fileMode, err := parseFileMode(*mode)
+	if err != nil {
+		fmt.Printf("Error: Invalid mode '%s'. Use an octal permission such as 0600\n", *mode)
+		os.Exit(1)
+	}
+
+	// Load master key when derivation is requested
+	var masterKey []byte
+	if *masterKeyFile != "" || *masterKeyEnv != "" {
+		key, err := generator.ReadMasterKey(*masterKeyFile, *masterKeyEnv)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		masterKey = key
+	}
+
 	// Create generator config
 	config := generator.Config{
-		TemplatePath: templatePath,
-		OutputPath:   *output,
-		Force:        *force,
-		ValueLength:  *length,
-		Charset:      charsetType,
+		TemplatePath:      templatePath,
+		Overlays:          overlays,
+		OutputPath:        *output,
+		Force:             *force,
+		ValueLength:       *length,
+		Charset:           charsetType,
+		MasterKey:         masterKey,
+		DeriveNamespace:   *deriveNamespace,
+		DeriveVersion:     *deriveVersion,
+		FileMode:          fileMode,
+		NoBackup:          *noBackup,
+		BackupRetention:   *keepBackups,
+		Format:            formatType,
+		NestSeparator:     *nest,
+		YAMLComments:      *yamlComments,
+		ManifestName:      *manifestName,
+		ManifestNamespace: *manifestNamespace,
+		EOL:               eolStyle,
+		MaxLineSize:       *maxLineSize,
+		LockTimeout:       *lockTimeout,
+		Dialect:           dialectType,
+		Profile:           *profile,
+	}
+	if project != nil {
+		config.Presets, config.KeyOverrides = project.Presets, project.Keys
+	}
+
+	// Derived values agree across runs by themselves, so only random ones are stored
+	if masterKey == nil {
+		storeDir := filepath.Dir(templatePath)
+		if *recursive {
+			storeDir = templatePath
+		}
+		store, err := sharedStorePath(*sharedStore, storeDir)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		config.Shared = generator.OpenSharedValues(store, *lockTimeout)
+	}
+
+	if *recursive {
+		if err := runHooks(hookProject, generator.HookPre, "", ""); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		code := runRecursive(templatePath, *pattern, *jobs, config, *yes)
+		if code == 0 {
+			if err := runHooks(hookProject, generator.HookPost, "", ""); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		os.Exit(code)
 	}
 
 	// Prompt for confirmation only when --force is used without --yes
@@ -96,14 +293,40 @@ func main() {
 		}
 	}
 
+	if err := runHooks(hookProject, generator.HookPre, templatePath, config.OutputPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create generator and generate .env file
 	gen := generator.New(config)
 	if err := gen.Generate(); err != nil {
+		var validationErr *generator.ValidationError
+		if errors.As(err, &validationErr) {
+			fmt.Printf("Generated %s from %s, but some values do not match the template schema:\n", config.OutputPath, strings.Join(args, ", "))
+			for _, violation := range validationErr.Violations {
+				fmt.Printf("  %s\n", violation)
+			}
+			os.Exit(1)
+		}
 		fmt.Printf("Error generating .env file: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Successfully generated %s from %s\n", config.OutputPath, templatePath)
+	for _, warning := range gen.Warnings() {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+
+	if backup := gen.BackupPath(); backup != "" {
+		fmt.Printf("Backed up previous file to %s\n", backup)
+	}
+
+	fmt.Printf("Successfully generated %s from %s\n", config.OutputPath, strings.Join(args, ", "))
+
+	if err := runHooks(hookProject, generator.HookPost, templatePath, config.OutputPath); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
 }
 
 // isValidCharset checks if the given charset is valid
@@ -118,6 +341,90 @@ func isValidCharset(charset generator.CharsetType) bool {
 	return validCharsets[charset]
 }
 
+// isValidFormat checks if the given output format is valid
+func isValidFormat(format generator.Format) bool {
+	switch format {
+	case generator.FormatDotenv, generator.FormatJSON, generator.FormatYAML,
+		generator.FormatK8sSecret, generator.FormatK8sSplit,
+		generator.FormatShell, generator.FormatFish, generator.FormatPowerShell:
+		return true
+	}
+	return false
+}
+
+// isValidDialect checks if the given dialect is valid
+func isValidDialect(dialect generator.Dialect) bool {
+	switch dialect {
+	case generator.DialectDotenv, generator.DialectDocker, generator.DialectCompose, generator.DialectSystemd:
+		return true
+	}
+	return false
+}
+
+// defaultOutputPath returns the output path used when --output is not given
+func defaultOutputPath(format generator.Format) string {
+	switch format {
+	case generator.FormatDotenv:
+		return ".env"
+	case generator.FormatK8sSecret, generator.FormatK8sSplit:
+		return ".env.k8s.yaml"
+	case generator.FormatPowerShell:
+		return ".env.ps1"
+	default:
+		return ".env." + string(format)
+	}
+}
+
+// profileOutputPath inserts the profile into a default output path, e.g. .env.staging
+// or .env.staging.json
+func profileOutputPath(path, profile string) string {
+	if profile == "" {
+		return path
+	}
+	return ".env." + profile + strings.TrimPrefix(path, ".env")
+}
+
+// flagPassed checks if any of the named flags was set on the command line
+func flagPassed(names ...string) bool {
+	return flagSetPassed(flag.CommandLine, names...)
+}
+
+// flagSetPassed checks if any of the named flags was set explicitly on fs
+func flagSetPassed(fs *flag.FlagSet, names ...string) bool {
+	passed := false
+	fs.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				passed = true
+			}
+		}
+	})
+	return passed
+}
+
+// isValidEOL checks if the given line ending style is valid
+func isValidEOL(eol generator.EOLStyle) bool {
+	switch eol {
+	case generator.EOLAuto, generator.EOLLF, generator.EOLCRLF:
+		return true
+	}
+	return false
+}
+
+// parseFileMode parses an octal permission string; an empty string means unset
+func parseFileMode(mode string) (os.FileMode, error) {
+	if mode == "" {
+		return 0, nil
+	}
+
+	perm, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || perm == 0 || perm > 0777 {
+		return 0, fmt.Errorf("invalid file mode %q", mode)
+	}
+
+	return os.FileMode(perm), nil
+}
+
 // fileExists checks if a file exists
 func fileExists(path string) bool {
 	_, err := os.Stat(path)
@@ -126,7 +433,12 @@ func fileExists(path string) bool {
 
 // promptOverwrite prompts the user for confirmation to regenerate all values
 func promptOverwrite(path string) bool {
-	fmt.Printf("File %s already exists. Regenerate all values? (y/N): ", path)
+	return confirm(fmt.Sprintf("File %s already exists. Regenerate all values?", path))
+}
+
+// confirm asks the user a yes/no question, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s (y/N): ", question)
 	reader := bufio.NewReader(os.Stdin)
 	response, err := reader.ReadString('\n')
 	if err != nil {
@@ -137,23 +449,12 @@ func promptOverwrite(path string) bool {
 	return response == "y" || response == "yes"
 }
 
-func reorderArgs() {
-	if len(os.Args) <= 1 {
-		return
-	}
-
-	args := os.Args[1:]
+// reorderArgs moves flags in front of positional arguments so options may follow the template path
+// Whether a flag consumes the next argument is looked up in fs, so new bool flags need no extra wiring
+func reorderArgs(fs *flag.FlagSet, args []string) []string {
 	var flags []string
 	var positional []string
 
-	// Bool flags that don't take values
-	boolFlags := map[string]bool{
-		"-f": true, "--force": true,
-		"-y": true, "--yes": true,
-		"-v": true, "--version": true,
-		"-h": true, "--help": true,
-	}
-
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
 
@@ -162,7 +463,7 @@ func reorderArgs() {
 			flags = append(flags, arg)
 
 			// Check if this flag expects a value
-			if !boolFlags[arg] && !strings.Contains(arg, "=") {
+			if !isBoolFlag(fs, arg) && !strings.Contains(arg, "=") {
 				// Check if there's a next argument and it's not a flag
 				if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
 					i++
@@ -175,5 +476,15 @@ func reorderArgs() {
 		}
 	}
 
-	os.Args = append([]string{os.Args[0]}, append(flags, positional...)...)
+	return append(flags, positional...)
+}
+
+// isBoolFlag checks if arg names a flag in fs that does not take a value
+func isBoolFlag(fs *flag.FlagSet, arg string) bool {
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
 }
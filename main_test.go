@@ -621,6 +621,145 @@ func TestForceOption_PreservesNonPlaceholderValues(t *testing.T) {
 	}
 }
 
+// TestFormatOption tests the --format flag
+func TestFormatOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "DB__HOST=localhost\nDB__PASSWORD=${db_password}\n")
+	tmpDir := filepath.Dir(template)
+
+	// Without --output the file name follows the format
+	cmd := exec.Command(binary, "--format", "yaml", "--nest", "__", template)
+	cmd.Dir = tmpDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("genenv failed: %v\n%s", err, output)
+	}
+
+	content := readOutputFile(t, filepath.Join(tmpDir, ".env.yaml"))
+	assertContains(t, content, "db:\n  host: \"localhost\"\n  password: \"")
+
+	cmd = exec.Command(binary, "--format", "powershell", template)
+	cmd.Dir = tmpDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("genenv failed: %v\n%s", err, output)
+	}
+
+	content = readOutputFile(t, filepath.Join(tmpDir, ".env.ps1"))
+	assertContains(t, content, "$env:DB__HOST = 'localhost'\n")
+
+	exitCode, stdout, stderr := runGenenv(t, binary, "--format", "toml", template)
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code for invalid format")
+	}
+	assertContains(t, stdout+stderr, "Invalid format")
+}
+
+// TestDialectOption tests the --dialect flag
+func TestDialectOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "GREETING=\"hello world\"\nPRICE=\"$5 ${suffix}\"\n")
+	tmpDir := filepath.Dir(template)
+	output := filepath.Join(tmpDir, "compose.env")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "--dialect", "compose", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	assertNotContains(t, stdout, "Warning")
+
+	content := readOutputFile(t, output)
+	assertContains(t, content, "PRICE=$$5 ")
+
+	output = filepath.Join(tmpDir, "docker.env")
+	exitCode, stdout, _ = runGenenv(t, binary, "--dialect", "docker", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Warning: "+output+":1: GREETING is read differently by docker")
+	assertNotContains(t, stdout, "hello world")
+
+	exitCode, stdout, stderr := runGenenv(t, binary, "--dialect", "bash", "-o", output, template)
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code for invalid dialect")
+	}
+	assertContains(t, stdout+stderr, "Invalid dialect")
+}
+
+// TestEOLOption tests the --eol flag
+func TestEOLOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "TEST_KEY=${test}\nFIXED=1\n")
+	tmpDir := filepath.Dir(template)
+	output := filepath.Join(tmpDir, "output.env")
+
+	exitCode, _, _ := runGenenv(t, binary, "--eol", "crlf", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+
+	content := readOutputFile(t, output)
+	if strings.Count(content, "\r\n") != 2 {
+		t.Errorf("Expected CRLF line endings, got %q", content)
+	}
+
+	exitCode, stdout, stderr := runGenenv(t, binary, "--eol", "cr", "-o", output, template)
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code for invalid eol")
+	}
+	assertContains(t, stdout+stderr, "Invalid eol")
+}
+
+// TestModeOption tests the --mode flag
+func TestModeOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "TEST_KEY=${test}")
+	tmpDir := filepath.Dir(template)
+
+	// New files default to 0600
+	defaultOutput := filepath.Join(tmpDir, "default.env")
+	exitCode, _, _ := runGenenv(t, binary, "-o", defaultOutput, template)
+	assertExitCode(t, exitCode, 0)
+
+	info, err := os.Stat(defaultOutput)
+	if err != nil {
+		t.Fatalf("Failed to stat output: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected default mode 600, got %o", info.Mode().Perm())
+	}
+
+	// --mode overrides the permissions
+	output := filepath.Join(tmpDir, "output.env")
+	exitCode, _, _ = runGenenv(t, binary, "--mode", "0640", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+
+	info, err = os.Stat(output)
+	if err != nil {
+		t.Fatalf("Failed to stat output: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected mode 640, got %o", info.Mode().Perm())
+	}
+}
+
+// TestModeOption_Invalid tests that --mode rejects values that aren't octal permissions
+func TestModeOption_Invalid(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "TEST_KEY=${test}")
+	output := filepath.Join(filepath.Dir(template), "output.env")
+
+	exitCode, stdout, stderr := runGenenv(t, binary, "--mode", "rw-r--r--", "-o", output, template)
+
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code for invalid mode")
+	}
+	assertContains(t, stdout+stderr, "Invalid mode")
+	assertFileNotExists(t, output)
+}
+
 // TestVersionOption tests the -v/--version flag
 func TestVersionOption_ShortForm(t *testing.T) {
 	binary, cleanup := buildBinary(t)
@@ -974,3 +1113,105 @@ func TestEdgeCase_VeryLongLength(t *testing.T) {
 	envVars := parseEnvFile(content)
 	assertValueLength(t, envVars["TEST_KEY"], 256)
 }
+
+// TestOverlayTemplates tests layering several templates into one output
+func TestOverlayTemplates(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	base := createTempTemplate(t, "DB_PASSWORD=${db_password}\nLOG_LEVEL=info\n")
+	overlay := filepath.Join(filepath.Dir(base), "api.env")
+	os.WriteFile(overlay, []byte("LOG_LEVEL=debug\nAPI_DB_URL=postgres://api:${db_password}@db/api\n"), 0644)
+	output := filepath.Join(filepath.Dir(base), "output.env")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "-o", output, base, overlay)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "from "+base+", "+overlay)
+
+	env := parseEnvFile(readOutputFile(t, output))
+	if env["LOG_LEVEL"] != "debug" {
+		t.Errorf("Expected the overlay to override LOG_LEVEL, got %q", env["LOG_LEVEL"])
+	}
+	if env["API_DB_URL"] != "postgres://api:"+env["DB_PASSWORD"]+"@db/api" {
+		t.Errorf("Expected layers to share ${db_password}, got %q", env["API_DB_URL"])
+	}
+
+	exitCode, stdout, _ = runGenenv(t, binary, "check", "-o", output, base, overlay)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "matches")
+}
+
+// TestProfileOption tests the --profile flag
+func TestProfileOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "LOG_LEVEL=info\nLOG_LEVEL=warn # @profile staging\nSECRET=${secret}\n")
+	dir := filepath.Dir(template)
+
+	cmd := exec.Command(binary, "--profile", "staging", template)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("genenv --profile failed: %v\n%s", err, out)
+	}
+
+	env := parseEnvFile(readOutputFile(t, filepath.Join(dir, ".env.staging")))
+	if env["LOG_LEVEL"] != "warn" {
+		t.Errorf("Expected the staging LOG_LEVEL, got %q", env["LOG_LEVEL"])
+	}
+	if len(env["SECRET"]) != 24 {
+		t.Errorf("Expected a generated SECRET, got %q", env["SECRET"])
+	}
+
+	exitCode, stdout, _ := runGenenv(t, binary, "--profile", "../prod", template)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "Invalid profile")
+}
+
+// TestRecursiveOption tests generating every template under a directory
+func TestRecursiveOption(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":                    "ignored/\n",
+		"api/.env.example":              "JWT_SECRET=${shared:jwt_secret}\n",
+		"gateway/.env.example":          "JWT_SECRET=${shared:jwt_secret}\n",
+		"ignored/.env.example":          "JWT_SECRET=${shared:jwt_secret}\n",
+		"services/broken/.env.example":  "BAD=${x:length=0}\n",
+		"services/billing/.env.example": "BILLING_KEY=${billing_key}\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	exitCode, stdout, _ := runGenenv(t, binary, "--recursive", root, "--jobs", "2")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "Processed 4 templates under "+root+": 3 generated, 1 failed")
+	assertContains(t, stdout, "Error generating "+filepath.Join(root, "services", "broken", ".env"))
+
+	api := parseEnvFile(readOutputFile(t, filepath.Join(root, "api", ".env")))
+	gateway := parseEnvFile(readOutputFile(t, filepath.Join(root, "gateway", ".env")))
+	if api["JWT_SECRET"] == "" || api["JWT_SECRET"] != gateway["JWT_SECRET"] {
+		t.Errorf("Expected one shared JWT secret, got %q and %q", api["JWT_SECRET"], gateway["JWT_SECRET"])
+	}
+	assertFileExists(t, filepath.Join(root, "services", "billing", ".env"))
+	assertFileNotExists(t, filepath.Join(root, "ignored", ".env"))
+
+	// Without broken templates the run succeeds
+	os.Remove(filepath.Join(root, "services", "broken", ".env.example"))
+	exitCode, stdout, _ = runGenenv(t, binary, "--recursive", root, "--pattern", "api/*.example", "-o", ".env.local")
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "1 generated, 0 failed")
+	assertFileExists(t, filepath.Join(root, "api", ".env.local"))
+	assertFileNotExists(t, filepath.Join(root, "gateway", ".env.local"))
+
+	exitCode, stdout, _ = runGenenv(t, binary, "--recursive", root, "--pattern", "*.missing")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "No templates matching *.missing")
+}
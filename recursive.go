@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runRecursive implements `genenv --recursive`, which generates the output next to every
+// template found under root
+// base holds the options for every template; its OutputPath is relative to each template.
+func runRecursive(root, pattern string, jobs int, base generator.Config, yes bool) int {
+	if filepath.IsAbs(base.OutputPath) {
+		fmt.Printf("Error: --output must be relative to each template with --recursive\n")
+		return 1
+	}
+
+	templates, err := generator.FindTemplates(root, pattern)
+	if err != nil {
+		fmt.Printf("Error finding templates: %v\n", err)
+		return 1
+	}
+	if len(templates) == 0 {
+		fmt.Printf("Error: No templates matching %s found under %s\n", pattern, root)
+		return 1
+	}
+
+	configs := make([]generator.Config, len(templates))
+	existing := 0
+	for i, template := range templates {
+		config := base
+		config.TemplatePath = template
+		config.OutputPath = filepath.Join(filepath.Dir(template), base.OutputPath)
+		configs[i] = config
+		if fileExists(config.OutputPath) {
+			existing++
+		}
+	}
+
+	// Ask once for all outputs rather than for each of them
+	if base.Force && !yes && existing > 0 {
+		if !confirm(fmt.Sprintf("%d output files already exist. Regenerate all values?", existing)) {
+			fmt.Println("Operation cancelled")
+			return 0
+		}
+	}
+
+	failed := 0
+	for _, result := range generator.GenerateAll(configs, jobs) {
+		config := result.Config
+		for _, warning := range result.Warnings {
+			fmt.Printf("Warning: %s: %s\n", config.TemplatePath, warning)
+		}
+
+		var validationErr *generator.ValidationError
+		switch {
+		case errors.As(result.Err, &validationErr):
+			failed++
+			fmt.Printf("Generated %s from %s, but some values do not match the template schema:\n", config.OutputPath, config.TemplatePath)
+			for _, violation := range validationErr.Violations {
+				fmt.Printf("  %s\n", violation)
+			}
+		case result.Err != nil:
+			failed++
+			fmt.Printf("Error generating %s from %s: %v\n", config.OutputPath, config.TemplatePath, result.Err)
+		default:
+			if result.BackupPath != "" {
+				fmt.Printf("Backed up previous file to %s\n", result.BackupPath)
+			}
+			fmt.Printf("Generated %s from %s\n", config.OutputPath, config.TemplatePath)
+		}
+	}
+
+	fmt.Printf("Processed %d templates under %s: %d generated, %d failed\n", len(templates), root, len(templates)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
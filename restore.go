@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runRestore implements `genenv restore`, which lists backups or puts one back in place
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+
+	list := fs.Bool("list", false, "List available backups")
+	latest := fs.Bool("latest", false, "Restore the most recent backup")
+
+	output := fs.String("output", ".env", "Output file whose backups are used")
+	fs.StringVar(output, "o", ".env", "Output file whose backups are used")
+
+	mode := fs.String("mode", "", "Permissions of the restored file in octal (default: keep existing)")
+	noBackup := fs.Bool("no-backup", false, "Do not back up the current file before restoring")
+	keepBackups := fs.Int("keep-backups", generator.DefaultBackupRetention, "Number of backups to keep per output file")
+	lockTimeout := fs.Duration("lock-timeout", generator.DefaultLockTimeout, "How long to wait for another genenv run writing the same output")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv restore [--list|--latest|<id>] [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv restore --list\n")
+		fmt.Fprintf(os.Stderr, "  genenv restore --latest\n")
+		fmt.Fprintf(os.Stderr, "  genenv restore 20261016T120000 -o .env.production\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+
+	fileMode, err := parseFileMode(*mode)
+	if err != nil {
+		fmt.Printf("Error: Invalid mode '%s'. Use an octal permission such as 0600\n", *mode)
+		return 1
+	}
+
+	gen := generator.New(generator.Config{
+		OutputPath:      *output,
+		FileMode:        fileMode,
+		NoBackup:        *noBackup,
+		BackupRetention: *keepBackups,
+		LockTimeout:     *lockTimeout,
+	})
+
+	if *list {
+		backups, err := gen.Backups()
+		if err != nil {
+			fmt.Printf("Error listing backups: %v\n", err)
+			return 1
+		}
+		if len(backups) == 0 {
+			fmt.Printf("No backups found for %s\n", *output)
+			return 0
+		}
+		for _, backup := range backups {
+			fmt.Printf("%s  %s\n", backup.ID, backup.Time.Format("2006-01-02 15:04:05"))
+		}
+		return 0
+	}
+
+	var id string
+	switch {
+	case fs.NArg() == 1 && !*latest:
+		id = fs.Arg(0)
+	case fs.NArg() == 0 && *latest:
+		id = ""
+	default:
+		fs.Usage()
+		return 1
+	}
+
+	backup, err := gen.Restore(id)
+	if err != nil {
+		fmt.Printf("Error restoring backup: %v\n", err)
+		return 1
+	}
+
+	if saved := gen.BackupPath(); saved != "" {
+		fmt.Printf("Backed up previous file to %s\n", saved)
+	}
+
+	for _, secret := range backup.Secrets {
+		fmt.Printf("Restored secret file %s\n", secret)
+	}
+	fmt.Printf("Successfully restored %s from backup %s\n", *output, backup.ID)
+	return 0
+}
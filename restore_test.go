@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRestoreCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "TEST_KEY=${test}")
+	tmpDir := filepath.Dir(template)
+	output := filepath.Join(tmpDir, "output.env")
+
+	os.WriteFile(output, []byte("TEST_KEY=old_value\n"), 0600)
+
+	exitCode, stdout, _ := runGenenv(t, binary, "-f", "-y", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Backed up previous file to")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "restore", "--list", "-o", output)
+	assertExitCode(t, exitCode, 0)
+	id := strings.Fields(stdout)[0]
+
+	exitCode, stdout, _ = runGenenv(t, binary, "restore", id, "-o", output)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Successfully restored")
+
+	envVars := parseEnvFile(readOutputFile(t, output))
+	if envVars["TEST_KEY"] != "old_value" {
+		t.Errorf("Expected old_value to be restored, got %s", envVars["TEST_KEY"])
+	}
+}
+
+// TestRestoreCommand_SecretFiles tests that restoring brings back the secret files --force replaced
+func TestRestoreCommand_SecretFiles(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	template := createTempTemplate(t, "DB_PASSWORD_FILE=${db_pw:file=./secrets/db_pw}\n")
+	tmpDir := filepath.Dir(template)
+	output := filepath.Join(tmpDir, "output.env")
+	secret := filepath.Join(tmpDir, "secrets", "db_pw")
+
+	exitCode, _, _ := runGenenv(t, binary, "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	original := readOutputFile(t, secret)
+
+	exitCode, _, _ = runGenenv(t, binary, "-f", "-y", "-o", output, template)
+	assertExitCode(t, exitCode, 0)
+	if readOutputFile(t, secret) == original {
+		t.Fatalf("Expected --force to replace the secret")
+	}
+
+	exitCode, stdout, _ := runGenenv(t, binary, "restore", "--latest", "-o", output)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Restored secret file "+secret)
+	if restored := readOutputFile(t, secret); restored != original {
+		t.Errorf("Expected the previous secret to be restored, got %q", restored)
+	}
+}
+
+func TestRestoreCommand_NoBackups(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	output := filepath.Join(t.TempDir(), "output.env")
+
+	exitCode, stdout, _ := runGenenv(t, binary, "restore", "--list", "-o", output)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "No backups found")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "restore", "--latest", "-o", output)
+	if exitCode == 0 {
+		t.Error("Expected non-zero exit code when there is nothing to restore")
+	}
+	assertContains(t, stdout, "Error")
+}
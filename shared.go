@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yashikota/genenv/internal/generator"
+)
+
+// runShared implements `genenv shared`, which manages the store of ${shared:...} values
+// Values are never printed, only their names and shapes.
+func runShared(args []string) int {
+	fs := flag.NewFlagSet("shared", flag.ExitOnError)
+
+	store := fs.String("store", "", "Store of shared values (default: "+generator.SharedStoreDir+"/shared.json in the project root)")
+	lockTimeout := fs.Duration("lock-timeout", generator.DefaultLockTimeout, "How long to wait for another genenv run writing the store")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: genenv shared list [options]\n")
+		fmt.Fprintf(os.Stderr, "       genenv shared rotate <name...> [options]\n")
+		fmt.Fprintf(os.Stderr, "       genenv shared rm <name...> [options]\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExamples:\n")
+		fmt.Fprintf(os.Stderr, "  genenv shared list\n")
+		fmt.Fprintf(os.Stderr, "  genenv shared rotate jwt_secret && genenv --recursive --force --yes\n")
+	}
+
+	fs.Parse(reorderArgs(fs, args))
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 1
+	}
+	action, names := fs.Arg(0), fs.Args()[1:]
+
+	path, err := sharedStorePath(*store, ".")
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return 1
+	}
+	values := generator.OpenSharedValues(path, *lockTimeout)
+
+	switch action {
+	case "list":
+		if len(names) > 0 {
+			fs.Usage()
+			return 1
+		}
+		entries, err := values.Entries()
+		if err != nil {
+			fmt.Printf("Error listing shared values: %v\n", err)
+			return 1
+		}
+		if len(entries) == 0 {
+			fmt.Printf("No shared values in %s\n", path)
+			return 0
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s  %d %s  %s\n", entry.Name, entry.Length, entry.Charset, entry.Updated.Local().Format("2006-01-02 15:04:05"))
+		}
+		return 0
+
+	case "rotate", "rm":
+		if len(names) == 0 {
+			fs.Usage()
+			return 1
+		}
+		for _, name := range names {
+			if action == "rotate" {
+				err = values.Rotate(name)
+			} else {
+				err = values.Remove(name)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				return 1
+			}
+		}
+		if action == "rotate" {
+			fmt.Printf("Rotated %s in %s; regenerate outputs with --force to use the new values\n", strings.Join(names, ", "), path)
+		} else {
+			fmt.Printf("Removed %s from %s\n", strings.Join(names, ", "), path)
+		}
+		return 0
+
+	default:
+		fmt.Printf("Error: Unknown action '%s'. Valid actions are: list, rotate, rm\n", action)
+		return 1
+	}
+}
+
+// sharedStorePath returns the store of shared values given with a flag, or else the store
+// found for dir
+func sharedStorePath(flagValue, dir string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	return generator.FindSharedStore(dir)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestSharedCommand tests sharing values between separate runs and managing the store
+func TestSharedCommand(t *testing.T) {
+	binary, cleanup := buildBinary(t)
+	defer cleanup()
+
+	root := t.TempDir()
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	for _, service := range []string{"api", "gateway"} {
+		os.Mkdir(filepath.Join(root, service), 0755)
+		if err := os.WriteFile(filepath.Join(root, service, ".env.example"), []byte("JWT_SECRET=${shared:jwt_secret}\n"), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+	}
+
+	// Each service is generated from its own directory
+	for _, service := range []string{"api", "gateway"} {
+		cmd := exec.Command(binary, ".env.example")
+		cmd.Dir = filepath.Join(root, service)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("genenv failed in %s: %v\n%s", service, err, out)
+		}
+	}
+	api := parseEnvFile(readOutputFile(t, filepath.Join(root, "api", ".env")))
+	gateway := parseEnvFile(readOutputFile(t, filepath.Join(root, "gateway", ".env")))
+	if api["JWT_SECRET"] == "" || api["JWT_SECRET"] != gateway["JWT_SECRET"] {
+		t.Fatalf("Expected one shared JWT secret, got %q and %q", api["JWT_SECRET"], gateway["JWT_SECRET"])
+	}
+	store := filepath.Join(root, ".genenv", "shared.json")
+	assertFileExists(t, store)
+
+	exitCode, stdout, _ := runGenenv(t, binary, "shared", "list", "--store", store)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "jwt_secret  24 alphanumeric")
+	assertNotContains(t, stdout, api["JWT_SECRET"])
+
+	exitCode, stdout, _ = runGenenv(t, binary, "shared", "rotate", "jwt_secret", "--store", store)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "Rotated jwt_secret")
+	assertNotContains(t, stdout, api["JWT_SECRET"])
+
+	exitCode, _, _ = runGenenv(t, binary, "--force", "--yes", "--no-backup", filepath.Join(root, "api", ".env.example"), "-o", filepath.Join(root, "api", ".env"))
+	assertExitCode(t, exitCode, 0)
+	rotated := parseEnvFile(readOutputFile(t, filepath.Join(root, "api", ".env")))
+	if rotated["JWT_SECRET"] == api["JWT_SECRET"] {
+		t.Errorf("Expected the rotated value after --force")
+	}
+
+	exitCode, stdout, _ = runGenenv(t, binary, "shared", "rm", "missing", "--store", store)
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "no shared value named missing")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "shared", "rm", "jwt_secret", "--store", store)
+	assertExitCode(t, exitCode, 0)
+	exitCode, stdout, _ = runGenenv(t, binary, "shared", "list", "--store", store)
+	assertExitCode(t, exitCode, 0)
+	assertContains(t, stdout, "No shared values")
+
+	exitCode, stdout, _ = runGenenv(t, binary, "shared", "show")
+	assertExitCode(t, exitCode, 1)
+	assertContains(t, stdout, "Unknown action")
+}